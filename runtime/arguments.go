@@ -0,0 +1,120 @@
+package runtime
+
+// argumentsObject implements the internal methods of mapped arguments
+// exotic objects: while an index is mapped, its property and the
+// binding of the corresponding parameter are kept in sync.
+type argumentsObject struct {
+	ordinary
+	env    Environment
+	mapped map[PropertyKey]string // index to parameter name
+}
+
+// CreateUnmappedArgumentsObject creates the arguments object of strict
+// functions and functions with non-simple parameter lists.
+func (r *Realm) CreateUnmappedArgumentsObject(args []Value) *Object {
+	o := NewObject(r.ObjectPrototype)
+	o.DefineOwnProperty(StringKey("length"), DataDescriptor(Number(float64(len(args))), true, false, true))
+	for i, v := range args {
+		CreateDataProperty(o, StringKey(numberToString(float64(i))), v)
+	}
+	o.DefineOwnProperty(SymbolKey(SymbolIterator), DataDescriptor(r.ArrayValues.Value(), true, false, true))
+	thrower := r.ThrowTypeError.Value()
+	o.DefineOwnProperty(StringKey("callee"), AccessorDescriptor(thrower, thrower, false, false))
+	return o
+}
+
+// CreateMappedArgumentsObject creates the arguments object of sloppy
+// functions with simple parameter lists. formals are the parameter
+// names of f, bound in env.
+func (r *Realm) CreateMappedArgumentsObject(f *Object, formals []string, args []Value, env Environment) *Object {
+	m := &argumentsObject{env: env, mapped: make(map[PropertyKey]string)}
+	o := newExoticObject(m, r.ObjectPrototype)
+	for i, v := range args {
+		CreateDataProperty(o, StringKey(numberToString(float64(i))), v)
+	}
+	o.DefineOwnProperty(StringKey("length"), DataDescriptor(Number(float64(len(args))), true, false, true))
+
+	// With duplicate parameter names, the last one wins
+	seen := make(map[string]bool)
+	for i := len(formals) - 1; i >= 0; i-- {
+		name := formals[i]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if i < len(args) {
+			m.mapped[StringKey(numberToString(float64(i)))] = name
+		}
+	}
+
+	o.DefineOwnProperty(SymbolKey(SymbolIterator), DataDescriptor(r.ArrayValues.Value(), true, false, true))
+	o.DefineOwnProperty(StringKey("callee"), DataDescriptor(f.Value(), true, false, true))
+	return o
+}
+
+// read returns the value of the parameter mapped to key.
+func (m *argumentsObject) read(key PropertyKey) Value {
+	v, _ := m.env.GetBindingValue(m.mapped[key], false)
+	return v
+}
+
+func (m *argumentsObject) getOwnProperty(o *Object, key PropertyKey) (PropertyDescriptor, bool) {
+	desc, ok := m.ordinary.getOwnProperty(o, key)
+	if !ok {
+		return desc, false
+	}
+	if _, isMapped := m.mapped[key]; isMapped {
+		desc.Value = m.read(key)
+	}
+	return desc, true
+}
+
+func (m *argumentsObject) defineOwnProperty(o *Object, key PropertyKey, desc PropertyDescriptor) bool {
+	_, isMapped := m.mapped[key]
+	newDesc := desc
+	if isMapped && desc.IsDataDescriptor() && !desc.Has(HasValue) && desc.Has(HasWritable) && !desc.Writable {
+		newDesc.Value = m.read(key)
+		newDesc.Fields |= HasValue
+	}
+	if !m.ordinary.defineOwnProperty(o, key, newDesc) {
+		return false
+	}
+
+	if isMapped {
+		if desc.IsAccessorDescriptor() {
+			delete(m.mapped, key)
+		} else {
+			if desc.Has(HasValue) {
+				m.env.SetMutableBinding(m.mapped[key], desc.Value, false)
+			}
+			if desc.Has(HasWritable) && !desc.Writable {
+				delete(m.mapped, key)
+			}
+		}
+	}
+	return true
+}
+
+func (m *argumentsObject) get(o *Object, key PropertyKey, receiver Value) (Value, error) {
+	if _, isMapped := m.mapped[key]; isMapped {
+		return m.read(key), nil
+	}
+	return m.ordinary.get(o, key, receiver)
+}
+
+func (m *argumentsObject) set(o *Object, key PropertyKey, v Value, receiver Value) (bool, error) {
+	if _, isMapped := m.mapped[key]; isMapped && receiver.AsObject() == o {
+		if err := m.env.SetMutableBinding(m.mapped[key], v, false); err != nil {
+			return false, err
+		}
+	}
+	return m.ordinary.set(o, key, v, receiver)
+}
+
+func (m *argumentsObject) delete(o *Object, key PropertyKey) bool {
+	if !m.ordinary.delete(o, key) {
+		return false
+	}
+	delete(m.mapped, key)
+	return true
+}
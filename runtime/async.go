@@ -0,0 +1,427 @@
+package runtime
+
+// Await waits for v to settle without blocking: resume is called from a
+// promise job with ResumeNext and the fulfillment value, or ResumeThrow
+// and the rejection reason. The error of converting v to a promise is
+// returned, and resume is then never called.
+func (r *Realm) Await(v Value, resume func(mode ResumeMode, v Value)) error {
+	p, err := r.PromiseResolve(r.PromiseConstructor, v)
+	if err != nil {
+		return err
+	}
+	onFulfilled := r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		resume(ResumeNext, argument(args, 0))
+		return Undefined(), nil
+	})
+	onRejected := r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		resume(ResumeThrow, argument(args, 0))
+		return Undefined(), nil
+	})
+	r.PerformPromiseThen(p, onFulfilled.Value(), onRejected.Value(), nil)
+	return nil
+}
+
+// runAsync runs step, awaiting the values it returns with StepAwait,
+// until the body yields or completes. done is then called with the
+// final step.
+func (r *Realm) runAsync(step GeneratorStep, mode ResumeMode, v Value, done func(Value, StepResult, error)) {
+	for {
+		value, result, err := step(mode, v)
+		if err != nil || result != StepAwait {
+			done(value, result, err)
+			return
+		}
+		err = r.Await(value, func(mode ResumeMode, v Value) {
+			r.runAsync(step, mode, v, done)
+		})
+		if err == nil {
+			return
+		}
+		mode, v = ResumeThrow, r.ThrownValue(err)
+	}
+}
+
+// NewAsyncFunction creates an async function. Calling it runs the body
+// until its first await and returns a promise for its result. The step
+// function of the body returns StepAwait to await a value, and is
+// resumed with the outcome as it is at a yield.
+func (r *Realm) NewAsyncFunction(start GeneratorStart, env Environment, thisMode ThisMode) *Object {
+	f := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		capability := r.NewPromise()
+		step, err := start(f, env, args)
+		if err != nil {
+			_, err = Call(capability.Reject, Undefined(), r.ThrownValue(err))
+			return capability.Promise.Value(), err
+		}
+		r.runAsync(step, ResumeNext, Undefined(), func(v Value, _ StepResult, err error) {
+			if err != nil {
+				Call(capability.Reject, Undefined(), r.ThrownValue(err))
+			} else {
+				Call(capability.Resolve, Undefined(), v)
+			}
+		})
+		return capability.Promise.Value(), nil
+	}, env, thisMode)
+	f.SetPrototypeOf(r.AsyncFunctionPrototype)
+	return f
+}
+
+// asyncGeneratorState is the [[AsyncGeneratorState]] of an async
+// generator object.
+type asyncGeneratorState uint8
+
+// Definition of async generator states
+const (
+	asyncGeneratorSuspendedStart asyncGeneratorState = iota
+	asyncGeneratorSuspendedYield
+	asyncGeneratorExecuting
+	asyncGeneratorAwaitingReturn
+	asyncGeneratorCompleted
+)
+
+// asyncGeneratorRequest is a call to next, return or throw waiting for
+// the generator to handle it.
+type asyncGeneratorRequest struct {
+	mode       ResumeMode
+	value      Value
+	capability *PromiseCapability
+}
+
+// asyncGenerator holds the internal slots of an async generator object.
+type asyncGenerator struct {
+	state asyncGeneratorState
+	step  GeneratorStep
+	queue []*asyncGeneratorRequest
+}
+
+// NewAsyncGeneratorFunction creates an async generator function. The
+// step function of its body returns StepAwait to await a value and
+// StepYield to yield one. Like the evaluator, it awaits the operands of
+// yield and return itself; yield* yields the values of inner results.
+func (r *Realm) NewAsyncGeneratorFunction(start GeneratorStart, env Environment, thisMode ThisMode) *Object {
+	f := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		step, err := start(f, env, args)
+		if err != nil {
+			return Undefined(), err
+		}
+		g, err := r.OrdinaryCreateFromConstructor(f, func(r *Realm) *Object {
+			return r.AsyncGeneratorPrototype
+		})
+		if err != nil {
+			return Undefined(), err
+		}
+		g.internal = &asyncGenerator{step: step}
+		return g.Value(), nil
+	}, env, thisMode)
+	f.SetPrototypeOf(r.AsyncGeneratorFunctionPrototype)
+	f.DefineOwnProperty(StringKey("prototype"), DataDescriptor(NewObject(r.AsyncGeneratorPrototype).Value(), true, false, false))
+	return f
+}
+
+// enqueue handles a call to next, return or throw of the async
+// generator g, implementing AsyncGeneratorEnqueue and the methods of
+// %AsyncGeneratorPrototype%. Requests made while g runs are queued.
+func (r *Realm) enqueue(g *asyncGenerator, mode ResumeMode, v Value) *Object {
+	capability := r.NewPromise()
+	if g.state == asyncGeneratorSuspendedStart && mode == ResumeThrow {
+		g.state = asyncGeneratorCompleted
+	}
+	if g.state == asyncGeneratorCompleted {
+		switch mode {
+		case ResumeNext:
+			Call(capability.Resolve, Undefined(), r.CreateIterResultObject(Undefined(), true))
+			return capability.Promise
+		case ResumeThrow:
+			Call(capability.Reject, Undefined(), v)
+			return capability.Promise
+		}
+	}
+
+	g.queue = append(g.queue, &asyncGeneratorRequest{mode: mode, value: v, capability: capability})
+	switch {
+	case mode == ResumeReturn && (g.state == asyncGeneratorSuspendedStart || g.state == asyncGeneratorCompleted):
+		// The body never runs
+		g.state = asyncGeneratorAwaitingReturn
+		r.asyncGeneratorAwaitReturn(g)
+	case g.state == asyncGeneratorSuspendedStart || g.state == asyncGeneratorSuspendedYield:
+		r.asyncGeneratorResume(g, mode, v)
+	}
+	return capability.Promise
+}
+
+// asyncGeneratorResume resumes the body of g with the completion of
+// the request in front of the queue. A return at a yield first awaits
+// the returned value, which throws at the yield if it rejects.
+func (r *Realm) asyncGeneratorResume(g *asyncGenerator, mode ResumeMode, v Value) {
+	g.state = asyncGeneratorExecuting
+	if mode != ResumeReturn {
+		r.asyncGeneratorRun(g, mode, v)
+		return
+	}
+	err := r.Await(v, func(mode ResumeMode, v Value) {
+		if mode == ResumeNext {
+			mode = ResumeReturn
+		}
+		r.asyncGeneratorRun(g, mode, v)
+	})
+	if err != nil {
+		r.asyncGeneratorRun(g, ResumeThrow, r.ThrownValue(err))
+	}
+}
+
+// asyncGeneratorRun runs the body of g until it yields or completes.
+func (r *Realm) asyncGeneratorRun(g *asyncGenerator, mode ResumeMode, v Value) {
+	r.runAsync(g.step, mode, v, func(value Value, result StepResult, err error) {
+		if err != nil || result == StepReturn {
+			g.state = asyncGeneratorCompleted
+			g.step = nil
+			r.asyncGeneratorCompleteStep(g, value, err, true)
+			r.asyncGeneratorDrainQueue(g)
+			return
+		}
+
+		// AsyncGeneratorYield: the body goes on with the next request
+		// if there is one
+		r.asyncGeneratorCompleteStep(g, value, nil, false)
+		if len(g.queue) == 0 {
+			g.state = asyncGeneratorSuspendedYield
+			return
+		}
+		next := g.queue[0]
+		r.asyncGeneratorResume(g, next.mode, next.value)
+	})
+}
+
+// asyncGeneratorCompleteStep settles the promise of the request in
+// front of the queue and removes it.
+func (r *Realm) asyncGeneratorCompleteStep(g *asyncGenerator, v Value, err error, done bool) {
+	next := g.queue[0]
+	g.queue[0] = nil
+	g.queue = g.queue[1:]
+	if err != nil {
+		Call(next.capability.Reject, Undefined(), r.ThrownValue(err))
+	} else {
+		Call(next.capability.Resolve, Undefined(), r.CreateIterResultObject(v, done))
+	}
+}
+
+// asyncGeneratorDrainQueue completes the requests queued before g
+// completed. A return request awaits its value, and the queue is
+// drained again once it settles.
+func (r *Realm) asyncGeneratorDrainQueue(g *asyncGenerator) {
+	for len(g.queue) > 0 {
+		next := g.queue[0]
+		switch next.mode {
+		case ResumeReturn:
+			g.state = asyncGeneratorAwaitingReturn
+			r.asyncGeneratorAwaitReturn(g)
+			return
+		case ResumeThrow:
+			r.asyncGeneratorCompleteStep(g, Undefined(), Throw(next.value), true)
+		default:
+			r.asyncGeneratorCompleteStep(g, Undefined(), nil, true)
+		}
+	}
+}
+
+// asyncGeneratorAwaitReturn awaits the value of the return request in
+// front of the queue of the completed generator g.
+func (r *Realm) asyncGeneratorAwaitReturn(g *asyncGenerator) {
+	err := r.Await(g.queue[0].value, func(mode ResumeMode, v Value) {
+		g.state = asyncGeneratorCompleted
+		if mode == ResumeThrow {
+			r.asyncGeneratorCompleteStep(g, Undefined(), Throw(v), true)
+		} else {
+			r.asyncGeneratorCompleteStep(g, v, nil, true)
+		}
+		r.asyncGeneratorDrainQueue(g)
+	})
+	if err != nil {
+		g.state = asyncGeneratorCompleted
+		r.asyncGeneratorCompleteStep(g, Undefined(), err, true)
+		r.asyncGeneratorDrainQueue(g)
+	}
+}
+
+// GetAsyncIterator returns an async iterator over v for for await,
+// calling its @@asyncIterator method, or else wrapping its sync
+// iterator with CreateAsyncFromSyncIterator.
+func (r *Realm) GetAsyncIterator(v Value) (*IteratorRecord, error) {
+	method, err := r.GetV(v, SymbolKey(SymbolAsyncIterator))
+	if err != nil {
+		return nil, err
+	}
+	if !method.IsNullish() {
+		if !IsCallable(method) {
+			return nil, throwTypeError("%s is not async iterable", v)
+		}
+		return GetIteratorFromMethod(v, method)
+	}
+	rec, err := r.GetIterator(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.CreateAsyncFromSyncIterator(rec), nil
+}
+
+// CreateAsyncFromSyncIterator wraps the sync iterator of rec in an
+// async iterator, which awaits the values it produces.
+func (r *Realm) CreateAsyncFromSyncIterator(rec *IteratorRecord) *IteratorRecord {
+	o := NewObject(r.AsyncFromSyncIteratorPrototype)
+	o.internal = rec
+	next, _ := Get(o, StringKey("next"))
+	return &IteratorRecord{Iterator: o, NextMethod: next}
+}
+
+// asyncFromSyncIteratorContinuation resolves the promise of capability
+// once the value of the sync iterator result settles. With
+// closeOnRejection, the sync iterator is closed if it rejects.
+func (r *Realm) asyncFromSyncIteratorContinuation(result *Object, capability *PromiseCapability, rec *IteratorRecord, closeOnRejection bool) (Value, error) {
+	done, err := IteratorComplete(result)
+	if err != nil {
+		return r.rejectCapability(capability, err)
+	}
+	value, err := IteratorValue(result)
+	if err != nil {
+		return r.rejectCapability(capability, err)
+	}
+	wrapper, err := r.PromiseResolve(r.PromiseConstructor, value)
+	if err != nil {
+		if !done && closeOnRejection {
+			err = IteratorClose(rec, err)
+		}
+		return r.rejectCapability(capability, err)
+	}
+
+	onFulfilled := r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		return r.CreateIterResultObject(argument(args, 0), done), nil
+	})
+	onRejected := Undefined()
+	if !done && closeOnRejection {
+		onRejected = r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+			return Undefined(), IteratorClose(rec, Throw(argument(args, 0)))
+		}).Value()
+	}
+	r.PerformPromiseThen(wrapper, onFulfilled.Value(), onRejected, capability)
+	return capability.Promise.Value(), nil
+}
+
+// rejectCapability rejects the promise of capability with the value
+// thrown by err, as IfAbruptRejectPromise.
+func (r *Realm) rejectCapability(capability *PromiseCapability, err error) (Value, error) {
+	if _, err := Call(capability.Reject, Undefined(), r.ThrownValue(err)); err != nil {
+		return Undefined(), err
+	}
+	return capability.Promise.Value(), nil
+}
+
+// initAsync creates the prototypes of async functions, async
+// generators and async iterators.
+func (r *Realm) initAsync() {
+	r.AsyncFunctionPrototype = NewObject(r.FunctionPrototype)
+	r.AsyncFunctionPrototype.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str("AsyncFunction"), false, false, true))
+
+	r.AsyncIteratorPrototype = NewObject(r.ObjectPrototype)
+	asyncIterator := r.NewBuiltinFunction("[Symbol.asyncIterator]", 0, func(this Value, args []Value) (Value, error) {
+		return this, nil
+	})
+	r.AsyncIteratorPrototype.DefineOwnProperty(SymbolKey(SymbolAsyncIterator), DataDescriptor(asyncIterator.Value(), true, false, true))
+
+	r.AsyncGeneratorFunctionPrototype = NewObject(r.FunctionPrototype)
+	r.AsyncGeneratorPrototype = NewObject(r.AsyncIteratorPrototype)
+	r.AsyncGeneratorFunctionPrototype.DefineOwnProperty(StringKey("prototype"), DataDescriptor(r.AsyncGeneratorPrototype.Value(), false, false, true))
+	r.AsyncGeneratorFunctionPrototype.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str("AsyncGeneratorFunction"), false, false, true))
+	r.AsyncGeneratorPrototype.DefineOwnProperty(StringKey("constructor"), DataDescriptor(r.AsyncGeneratorFunctionPrototype.Value(), false, false, true))
+	r.AsyncGeneratorPrototype.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str("AsyncGenerator"), false, false, true))
+
+	for _, m := range []struct {
+		name string
+		mode ResumeMode
+	}{{"next", ResumeNext}, {"return", ResumeReturn}, {"throw", ResumeThrow}} {
+		mode, name := m.mode, m.name
+		r.defineMethod(r.AsyncGeneratorPrototype, name, 1, func(this Value, args []Value) (Value, error) {
+			o := this.AsObject()
+			if o == nil || o.asyncGen() == nil {
+				// Invalid receivers reject rather than throw
+				capability := r.NewPromise()
+				return r.rejectCapability(capability, throwTypeError("%s method called on incompatible receiver %s", name, this))
+			}
+			return r.enqueue(o.asyncGen(), mode, argument(args, 0)).Value(), nil
+		})
+	}
+
+	r.AsyncFromSyncIteratorPrototype = NewObject(r.AsyncIteratorPrototype)
+	r.defineAsyncFromSyncMethod("next", func(rec *IteratorRecord, capability *PromiseCapability, args []Value) (Value, error) {
+		var v *Value
+		if len(args) > 0 {
+			v = &args[0]
+		}
+		result, err := IteratorNext(rec, v)
+		if err != nil {
+			return r.rejectCapability(capability, err)
+		}
+		return r.asyncFromSyncIteratorContinuation(result, capability, rec, true)
+	})
+	r.defineAsyncFromSyncMethod("return", func(rec *IteratorRecord, capability *PromiseCapability, args []Value) (Value, error) {
+		method, err := getMethod(rec.Iterator, StringKey("return"))
+		if err != nil {
+			return r.rejectCapability(capability, err)
+		}
+		if method.IsUndefined() {
+			Call(capability.Resolve, Undefined(), r.CreateIterResultObject(argument(args, 0), true))
+			return capability.Promise.Value(), nil
+		}
+		// Only the argument given, if any, is passed on
+		if len(args) > 1 {
+			args = args[:1]
+		}
+		result, err := Call(method, rec.Iterator.Value(), args...)
+		if err == nil && !result.IsObject() {
+			err = throwTypeError("Iterator result %s is not an object", result)
+		}
+		if err != nil {
+			return r.rejectCapability(capability, err)
+		}
+		return r.asyncFromSyncIteratorContinuation(result.AsObject(), capability, rec, false)
+	})
+	r.defineAsyncFromSyncMethod("throw", func(rec *IteratorRecord, capability *PromiseCapability, args []Value) (Value, error) {
+		method, err := getMethod(rec.Iterator, StringKey("throw"))
+		if err != nil {
+			return r.rejectCapability(capability, err)
+		}
+		if method.IsUndefined() {
+			// The protocol is violated: close the iterator and report it
+			if err := IteratorClose(rec, nil); err != nil {
+				return r.rejectCapability(capability, err)
+			}
+			return r.rejectCapability(capability, throwTypeError("The iterator does not provide a 'throw' method"))
+		}
+		// Only the argument given, if any, is passed on
+		if len(args) > 1 {
+			args = args[:1]
+		}
+		result, err := Call(method, rec.Iterator.Value(), args...)
+		if err == nil && !result.IsObject() {
+			err = throwTypeError("Iterator result %s is not an object", result)
+		}
+		if err != nil {
+			return r.rejectCapability(capability, err)
+		}
+		return r.asyncFromSyncIteratorContinuation(result.AsObject(), capability, rec, true)
+	})
+}
+
+// defineAsyncFromSyncMethod defines a method of
+// %AsyncFromSyncIteratorPrototype%. fn gets the wrapped sync iterator
+// and the capability of the promise the method returns, which is
+// rejected for other receivers.
+func (r *Realm) defineAsyncFromSyncMethod(name string, fn func(rec *IteratorRecord, capability *PromiseCapability, args []Value) (Value, error)) {
+	r.defineMethod(r.AsyncFromSyncIteratorPrototype, name, 1, func(this Value, args []Value) (Value, error) {
+		capability := r.NewPromise()
+		o := this.AsObject()
+		if o == nil || o.syncIter() == nil {
+			return r.rejectCapability(capability, throwTypeError("%s method called on incompatible receiver %s", name, this))
+		}
+		return fn(o.syncIter(), capability, args)
+	})
+}
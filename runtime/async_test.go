@@ -0,0 +1,271 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// settled returns the state and result of the promise p.
+func settled(p Value) (PromiseState, Value) {
+	return PromiseResult(p.AsObject())
+}
+
+func TestAsyncFunction(t *testing.T) {
+	r := NewRealm()
+
+	// async function f(x) {
+	//	try {
+	//		return await x + 1;
+	//	} catch (e) {
+	//		return "caught " + e;
+	//	}
+	// }
+	f := r.NewAsyncFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		x := argument(args, 0)
+		awaiting := false
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			if !awaiting {
+				awaiting = true
+				return x, StepAwait, nil
+			}
+			if mode == ResumeThrow {
+				return Str("caught " + v.String()), StepReturn, nil
+			}
+			return Number(v.AsNumber() + 1), StepReturn, nil
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+	test.AssertEqual(t, f.GetPrototypeOf(), r.AsyncFunctionPrototype)
+	test.AssertEqual(t, IsConstructor(f.Value()), false)
+
+	p, err := Call(f.Value(), Undefined(), Number(1))
+	test.AssertEqual(t, err, nil)
+	state, _ := settled(p)
+	test.AssertEqual(t, state, PromisePending)
+	r.RunJobs()
+	state, v := settled(p)
+	test.AssertEqual(t, state, PromiseFulfilled)
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+
+	// A rejected await throws at the await
+	rejected, _ := r.invoke(r.PromiseConstructor.Value(), "reject", Str("no"))
+	p, _ = Call(f.Value(), Undefined(), rejected)
+	r.RunJobs()
+	_, v = settled(p)
+	test.AssertEqual(t, v.String(), "caught no")
+
+	// Errors of the body reject the promise rather than throw
+	g := r.NewAsyncFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			return Undefined(), StepReturn, Throw(Str("thrown"))
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+	p, err = Call(g.Value(), Undefined())
+	test.AssertEqual(t, err, nil)
+	state, v = settled(p)
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, v.String(), "thrown")
+
+	// and so do errors of host code in the body
+	h := r.NewAsyncFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			return Undefined(), StepReturn, errors.New("host failure")
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+	p, err = Call(h.Value(), Undefined())
+	test.AssertEqual(t, err, nil)
+	state, v = settled(p)
+	test.AssertEqual(t, state, PromiseRejected)
+	message, _ := Get(v.AsObject(), StringKey("message"))
+	test.AssertEqual(t, message.String(), "host failure")
+}
+
+func TestAsyncGenerator(t *testing.T) {
+	r := NewRealm()
+	cleanups := 0
+
+	// async function* g() {
+	//	try {
+	//		yield 1;
+	//		yield await Promise.resolve(2);
+	//	} finally {
+	//		cleanups++;
+	//	}
+	// }
+	g := r.NewAsyncGeneratorFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		pc := 0
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			if mode != ResumeNext {
+				cleanups++
+				if mode == ResumeThrow {
+					return Undefined(), StepReturn, Throw(v)
+				}
+				return v, StepReturn, nil
+			}
+			pc++
+			switch pc {
+			case 1:
+				return Number(1), StepYield, nil
+			case 2:
+				p, _ := r.PromiseResolve(r.PromiseConstructor, Number(2))
+				return p.Value(), StepAwait, nil
+			case 3:
+				return v, StepYield, nil
+			}
+			cleanups++
+			return Undefined(), StepReturn, nil
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+
+	// Requests made before the first one settles are queued
+	it, err := Call(g.Value(), Undefined())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, it.AsObject().GetPrototypeOf().GetPrototypeOf(), r.AsyncGeneratorPrototype)
+	var log []string
+	for i := 0; i < 4; i++ {
+		p, _ := r.invoke(it, "next")
+		r.invoke(p, "then", r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+			result := args[0].AsObject()
+			v, _ := IteratorValue(result)
+			done, _ := IteratorComplete(result)
+			log = append(log, v.String()+map[bool]string{false: "", true: " done"}[done])
+			return Undefined(), nil
+		}).Value())
+	}
+	r.RunJobs()
+	test.AssertEqual(t, strings.Join(log, ", "), "1, 2, undefined done, undefined done")
+	test.AssertEqual(t, cleanups, 1)
+
+	// return at a yield awaits its value and runs the finally block
+	it, _ = Call(g.Value(), Undefined())
+	r.invoke(it, "next")
+	resolved, _ := r.PromiseResolve(r.PromiseConstructor, Str("early"))
+	p, _ := r.invoke(it, "return", resolved.Value())
+	r.RunJobs()
+	_, result := settled(p)
+	v, _ := IteratorValue(result.AsObject())
+	test.AssertEqual(t, v.String(), "early")
+	test.AssertEqual(t, cleanups, 2)
+
+	// Before the first next, the body never runs
+	it, _ = Call(g.Value(), Undefined())
+	p, _ = r.invoke(it, "throw", Str("boom"))
+	state, reason := settled(p)
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, reason.String(), "boom")
+	p, _ = r.invoke(it, "return", Number(3))
+	r.RunJobs()
+	_, result = settled(p)
+	v, _ = IteratorValue(result.AsObject())
+	test.AssertEqual(t, v.AsNumber(), 3.0)
+	test.AssertEqual(t, cleanups, 2)
+
+	// Invalid receivers reject
+	next, _ := Get(r.AsyncGeneratorPrototype, StringKey("next"))
+	p, err = Call(next, Number(1))
+	test.AssertEqual(t, err, nil)
+	state, _ = settled(p)
+	test.AssertEqual(t, state, PromiseRejected)
+}
+
+func TestForAwait(t *testing.T) {
+	r := NewRealm()
+
+	// async function f(iterable) {
+	//	let s = "";
+	//	for await (const x of iterable) s += x;
+	//	return s;
+	// }
+	f := r.NewAsyncFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		iterable := argument(args, 0)
+		var rec *IteratorRecord
+		s := ""
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			if mode == ResumeThrow {
+				return Undefined(), StepReturn, Throw(v)
+			}
+			if rec == nil {
+				var err error
+				if rec, err = r.GetAsyncIterator(iterable); err != nil {
+					return Undefined(), StepReturn, err
+				}
+			} else {
+				result := v.AsObject()
+				if result == nil {
+					return Undefined(), StepReturn, throwTypeError("Iterator result %s is not an object", v)
+				}
+				if done, _ := IteratorComplete(result); done {
+					return Str(s), StepReturn, nil
+				}
+				x, _ := IteratorValue(result)
+				s += x.String()
+			}
+			result, err := Call(rec.NextMethod, rec.Iterator.Value())
+			return result, StepAwait, err
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+
+	// Promises produced by a sync iterator are awaited
+	pending := r.NewPromise()
+	values := r.CreateUnmappedArgumentsObject([]Value{Str("a"), pending.Promise.Value(), Str("c")})
+	p, _ := Call(f.Value(), Undefined(), values.Value())
+	r.RunJobs()
+	state, _ := settled(p)
+	test.AssertEqual(t, state, PromisePending)
+	Call(pending.Resolve, Undefined(), Str("b"))
+	r.RunJobs()
+	state, v := settled(p)
+	test.AssertEqual(t, state, PromiseFulfilled)
+	test.AssertEqual(t, v.String(), "abc")
+
+	// A rejected value closes the sync iterator
+	closed := 0
+	it := newCountingIterator(r, 3, &closed, NewObject(nil).Value())
+	r.defineMethod(it, "next", 0, func(this Value, args []Value) (Value, error) {
+		rejected, _ := r.invoke(r.PromiseConstructor.Value(), "reject", Str("bad"))
+		return r.CreateIterResultObject(rejected, false), nil
+	})
+	p, _ = Call(f.Value(), Undefined(), it.Value())
+	r.RunJobs()
+	state, v = settled(p)
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, v.String(), "bad")
+	test.AssertEqual(t, closed, 1)
+
+	// Async iterators are used as is
+	rec, _ := r.GetAsyncIterator(NewObject(r.AsyncIteratorPrototype).Value())
+	test.AssertEqual(t, rec.Iterator.GetPrototypeOf(), r.AsyncIteratorPrototype)
+	rec, _ = r.GetAsyncIterator(Str("x"))
+	test.AssertEqual(t, rec.Iterator.GetPrototypeOf(), r.AsyncFromSyncIteratorPrototype)
+	_, err := r.GetAsyncIterator(Number(1))
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// return and throw pass on the argument only if one was given
+	var counts []int
+	sync := newCountingIterator(r, 1, &closed, NewObject(nil).Value())
+	for _, name := range []string{"return", "throw"} {
+		r.defineMethod(sync, name, 0, func(this Value, args []Value) (Value, error) {
+			counts = append(counts, len(args))
+			return r.CreateIterResultObject(Undefined(), true), nil
+		})
+	}
+	rec, _ = r.GetAsyncIterator(sync.Value())
+	for _, name := range []string{"return", "throw"} {
+		r.invoke(rec.Iterator.Value(), name)
+		r.invoke(rec.Iterator.Value(), name, Number(1), Number(2))
+	}
+	test.AssertEqual(t, fmt.Sprint(counts), "[0 1 0 1]")
+
+	// Methods of async-from-sync iterators reject other receivers
+	for _, name := range []string{"next", "return", "throw"} {
+		method, _ := Get(r.AsyncFromSyncIteratorPrototype, StringKey(name))
+		p, err = Call(method, NewObject(nil).Value())
+		test.AssertEqual(t, err, nil)
+		state, v = settled(p)
+		test.AssertEqual(t, state, PromiseRejected)
+		test.AssertEqual(t, v.AsObject().GetPrototypeOf(), r.nativeErrorPrototypes[TypeError])
+	}
+}
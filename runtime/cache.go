@@ -0,0 +1,123 @@
+package runtime
+
+// maxPolymorphism is the number of shapes an InlineCache remembers
+// before it becomes megamorphic.
+const maxPolymorphism = 4
+
+// maxCachedDepth is the longest prototype chain walk an InlineCache
+// records for inherited properties.
+const maxCachedDepth = 4
+
+// InlineCache speeds up a single property access site, such as the
+// o.x in an expression, by remembering where the property was found
+// for the shapes of the objects seen there. A cache holding one shape
+// is monomorphic, up to maxPolymorphism shapes it is polymorphic, and
+// beyond that it is megamorphic and every access takes the generic path.
+//
+// Only data properties of ordinary objects with shared shapes are
+// cached. Because a shape determines an object's prototype, an entry
+// for an inherited property is validated by comparing the shapes of
+// every object from the receiver to the holder of the property.
+type InlineCache struct {
+	entries     [maxPolymorphism]cacheEntry
+	n           int
+	megamorphic bool
+}
+
+// cacheEntry records that a property is stored in slot of the last
+// object of a prototype chain whose objects have the given shapes.
+type cacheEntry struct {
+	shapes [maxCachedDepth + 1]*shape
+	depth  int
+	slot   int
+	write  bool // valid for [[Set]]: the property is own and writable
+}
+
+// Get returns the value of the property key of o, like Get(o, key).
+func (c *InlineCache) Get(o *Object, key PropertyKey) (Value, error) {
+	for i := 0; i < c.n; i++ {
+		e := &c.entries[i]
+		if holder := e.match(o); holder != nil {
+			return holder.slots[e.slot], nil
+		}
+	}
+	if !c.megamorphic {
+		c.fill(o, key)
+	}
+	return Get(o, key)
+}
+
+// Set assigns v to the property key of o, like Set(o, key, v, throw).
+// Only assignments to existing writable own properties are cached.
+func (c *InlineCache) Set(o *Object, key PropertyKey, v Value, throw bool) error {
+	for i := 0; i < c.n; i++ {
+		e := &c.entries[i]
+		if e.write && e.depth == 0 && e.shapes[0] == o.shape {
+			o.slots[e.slot] = v
+			return nil
+		}
+	}
+	if err := Set(o, key, v, throw); err != nil {
+		return err
+	}
+	if !c.megamorphic {
+		c.fill(o, key)
+	}
+	return nil
+}
+
+// match returns the object holding the cached property if the
+// prototype chain of o has the shapes recorded in e, or nil.
+func (e *cacheEntry) match(o *Object) *Object {
+	for d := 0; ; d++ {
+		if o == nil || o.shape != e.shapes[d] {
+			return nil
+		}
+		if d == e.depth {
+			return o
+		}
+		o = o.proto
+	}
+}
+
+// fill records where key was found for the shape of o.
+func (c *InlineCache) fill(o *Object, key PropertyKey) {
+	var e cacheEntry
+	for h := o; ; h = h.proto {
+		if h == nil || e.depth > maxCachedDepth || !h.cacheable() {
+			return
+		}
+		e.shapes[e.depth] = h.shape
+		if slot := h.shape.lookup(key); slot >= 0 {
+			attrs := h.shape.attrs[slot]
+			if attrs&attrAccessor != 0 {
+				return
+			}
+			e.slot = slot
+			e.write = e.depth == 0 && attrs&attrWritable != 0
+			break
+		}
+		e.depth++
+	}
+
+	// Misses that the entry cannot serve, such as assignments adding
+	// a property, must not fill the cache with copies of it
+	for i := 0; i < c.n; i++ {
+		if c.entries[i] == e {
+			return
+		}
+	}
+	if c.n == maxPolymorphism {
+		c.megamorphic = true
+		c.n = 0
+		return
+	}
+	c.entries[c.n] = e
+	c.n++
+}
+
+// cacheable reports whether property lookups on o can be cached.
+func (o *Object) cacheable() bool {
+	_, ok := o.methods.(ordinary)
+	return ok && !o.shape.dictionary
+}
@@ -0,0 +1,267 @@
+package runtime
+
+// ClassElementKind is the kind of a class element.
+type ClassElementKind uint8
+
+// Definition of class element kinds
+const (
+	ClassMethod ClassElementKind = iota
+	ClassGetter
+	ClassSetter
+	ClassField
+	ClassStaticBlock
+)
+
+// ClassElement is an element of a class body whose computed key, if
+// any, has already been evaluated.
+type ClassElement struct {
+	Kind    ClassElementKind
+	Static  bool
+	Key     PropertyKey
+	Private *PrivateName // non-nil for private elements
+	Body    FunctionBody // method, initializer or static block; nil for fields without initializer
+}
+
+// ClassDefinition describes a class body for DefineClass.
+type ClassDefinition struct {
+	Name        string
+	HasHeritage bool
+	Heritage    Value        // value of the extends clause
+	Constructor FunctionBody // nil for the default constructor
+	Elements    []ClassElement
+	Env         Environment // environment the class is defined in
+}
+
+// classField is a field definition, run for each instance or once on
+// the class for static fields.
+type classField struct {
+	key         PropertyKey
+	private     *PrivateName
+	initializer *Object // method called with the receiver as this, or nil
+}
+
+// staticElement is a static field or a static initialization block.
+type staticElement struct {
+	field *classField
+	block *Object
+}
+
+// DefineClass creates the constructor of a class and its prototype,
+// defines their methods and accessors, and runs static field
+// initializers and static blocks in order, as ClassDefinitionEvaluation.
+func (r *Realm) DefineClass(def *ClassDefinition) (*Object, error) {
+	env := NewDeclarativeEnvironment(def.Env)
+	if def.Name != "" {
+		env.CreateImmutableBinding(def.Name, true)
+	}
+
+	protoParent, constructorParent := r.ObjectPrototype, r.FunctionPrototype
+	if def.HasHeritage {
+		superclass := def.Heritage
+		switch {
+		case superclass.IsNull():
+			protoParent = nil
+		case !IsConstructor(superclass):
+			return nil, throwTypeError("Class extends value %s is not a constructor or null", superclass)
+		default:
+			p, err := Get(superclass.AsObject(), StringKey("prototype"))
+			if err != nil {
+				return nil, err
+			}
+			if !p.IsObject() && !p.IsNull() {
+				return nil, throwTypeError("Class extends value does not have valid prototype property %s", p)
+			}
+			protoParent, constructorParent = p.AsObject(), superclass.AsObject()
+		}
+	}
+	proto := NewObject(protoParent)
+
+	body := def.Constructor
+	if body == nil {
+		body = defaultConstructor
+		if !def.HasHeritage {
+			body = func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return Undefined(), nil
+			}
+		}
+	}
+	f := r.NewFunction(body, env, ThisModeStrict)
+	f.SetPrototypeOf(constructorParent)
+	MakeMethod(f, proto)
+	f.function().classConstructor = true
+	if def.HasHeritage {
+		f.function().kind = DerivedConstructor
+	}
+	MakeConstructor(f, false, proto)
+	SetFunctionName(f, StringKey(def.Name), "")
+	proto.DefineOwnProperty(StringKey("constructor"), DataDescriptor(f.Value(), true, false, true))
+
+	var instanceMethods, staticMethods []*PrivateElement
+	var instanceFields []classField
+	var statics []staticElement
+	for _, e := range def.Elements {
+		home := proto
+		if e.Static {
+			home = f
+		}
+		var m *Object
+		if e.Body != nil {
+			m = r.NewFunction(e.Body, env, ThisModeStrict)
+			MakeMethod(m, home)
+		}
+
+		switch e.Kind {
+		case ClassField:
+			field := classField{key: e.Key, private: e.Private, initializer: m}
+			if e.Static {
+				statics = append(statics, staticElement{field: &field})
+			} else {
+				instanceFields = append(instanceFields, field)
+			}
+		case ClassStaticBlock:
+			statics = append(statics, staticElement{block: m})
+		default:
+			name := e.Key
+			if e.Private != nil {
+				name = StringKey(e.Private.description)
+			}
+			SetFunctionName(m, name, [...]string{ClassGetter: "get", ClassSetter: "set"}[e.Kind])
+			if e.Private == nil {
+				if err := defineMethodProperty(home, e.Key, e.Kind, m); err != nil {
+					return nil, err
+				}
+			} else if e.Static {
+				staticMethods = addPrivateMethod(staticMethods, e.Private, e.Kind, m)
+			} else {
+				instanceMethods = addPrivateMethod(instanceMethods, e.Private, e.Kind, m)
+			}
+		}
+	}
+
+	if def.Name != "" {
+		env.InitializeBinding(def.Name, f.Value())
+	}
+	f.function().privateMethods = instanceMethods
+	f.function().fields = instanceFields
+	for _, m := range staticMethods {
+		if err := PrivateMethodOrAccessorAdd(f, m); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range statics {
+		var err error
+		if s.field != nil {
+			err = defineField(f, s.field)
+		} else {
+			_, err = Call(s.block.Value(), f.Value())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// defaultConstructor is the body of constructor(...args) { super(...args); }.
+func defaultConstructor(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+	_, err := SuperCall(env, args)
+	return Undefined(), err
+}
+
+// defineMethodProperty defines a public method or accessor, which is
+// not enumerable.
+func defineMethodProperty(home *Object, key PropertyKey, kind ClassElementKind, m *Object) error {
+	desc := DataDescriptor(m.Value(), true, false, true)
+	switch kind {
+	case ClassGetter:
+		desc = PropertyDescriptor{Get: m.Value(), Configurable: true, Fields: HasGet | HasEnumerable | HasConfigurable}
+	case ClassSetter:
+		desc = PropertyDescriptor{Set: m.Value(), Configurable: true, Fields: HasSet | HasEnumerable | HasConfigurable}
+	}
+	return DefinePropertyOrThrow(home, key, desc)
+}
+
+// addPrivateMethod appends a private method to list, combining the
+// getter and setter of an accessor into a single element.
+func addPrivateMethod(list []*PrivateElement, p *PrivateName, kind ClassElementKind, m *Object) []*PrivateElement {
+	if kind == ClassMethod {
+		return append(list, &PrivateElement{Key: p, Kind: PrivateMethod, Value: m.Value()})
+	}
+	var e *PrivateElement
+	for _, x := range list {
+		if x.Key == p {
+			e = x
+		}
+	}
+	if e == nil {
+		e = &PrivateElement{Key: p, Kind: PrivateAccessor, Get: Undefined(), Set: Undefined()}
+		list = append(list, e)
+	}
+	if kind == ClassGetter {
+		e.Get = m.Value()
+	} else {
+		e.Set = m.Value()
+	}
+	return list
+}
+
+// defineField runs the initializer of a field with receiver as this
+// and defines the field on receiver.
+func defineField(receiver *Object, field *classField) error {
+	v := Undefined()
+	if field.initializer != nil {
+		var err error
+		v, err = Call(field.initializer.Value(), receiver.Value())
+		if err != nil {
+			return err
+		}
+	}
+	if field.private != nil {
+		return PrivateFieldAdd(receiver, field.private, v)
+	}
+	return CreateDataPropertyOrThrow(receiver, field.key, v)
+}
+
+// InitializeInstanceElements installs the private methods and fields
+// declared by the class constructor on the new instance o.
+func InitializeInstanceElements(o *Object, constructor *Object) error {
+	fn := constructor.function()
+	if fn == nil {
+		return nil
+	}
+	for _, m := range fn.privateMethods {
+		if err := PrivateMethodOrAccessorAdd(o, m); err != nil {
+			return err
+		}
+	}
+	for i := range fn.fields {
+		if err := defineField(o, &fn.fields[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SuperCall evaluates super(...args) in a derived constructor: it
+// constructs the instance with the parent constructor, binds this and
+// initializes the fields of the derived class.
+func SuperCall(env Environment, args []Value) (Value, error) {
+	thisEnv := GetThisEnvironment(env).(*FunctionEnvironment)
+	active := thisEnv.FunctionObject()
+	superConstructor := active.GetPrototypeOf()
+	if superConstructor == nil || superConstructor.construct == nil {
+		return Undefined(), throwTypeError("Super constructor is not a constructor")
+	}
+
+	result, err := Construct(superConstructor, args, thisEnv.NewTarget().AsObject())
+	if err != nil {
+		return Undefined(), err
+	}
+	if err := thisEnv.BindThisValue(result); err != nil {
+		return Undefined(), err
+	}
+	if err := InitializeInstanceElements(result.AsObject(), active); err != nil {
+		return Undefined(), err
+	}
+	return result, nil
+}
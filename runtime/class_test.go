@@ -0,0 +1,213 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// thisObject returns the this value bound in env.
+func thisObject(env *FunctionEnvironment) *Object {
+	this, _ := env.GetThisBinding()
+	return this.AsObject()
+}
+
+// newCounterClass defines, with a fresh private name #count:
+//
+//	class Counter {
+//		#count = 1;
+//		static created = 0;
+//		static { this.ready = true; }
+//		constructor() { Counter.created++; }
+//		#inc() { this.#count++; }
+//		bump() { this.#inc(); return this.#count; }
+//		get count() { return this.#count; }
+//		static has(o) { return #count in o; }
+//	}
+func newCounterClass(t *testing.T, r *Realm) (*Object, *PrivateName) {
+	count, inc := NewPrivateName("#count"), NewPrivateName("#inc")
+	var counter *Object
+	def := &ClassDefinition{
+		Name: "Counter",
+		Env:  r.GlobalEnv,
+		Constructor: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+			n, _ := Get(counter, StringKey("created"))
+			return Undefined(), Set(counter, StringKey("created"), Number(n.AsNumber()+1), true)
+		},
+		Elements: []ClassElement{
+			{Kind: ClassField, Private: count, Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return Number(1), nil
+			}},
+			{Kind: ClassField, Static: true, Key: StringKey("created"), Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return Number(0), nil
+			}},
+			{Kind: ClassStaticBlock, Static: true, Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return Undefined(), Set(thisObject(env), StringKey("ready"), Bool(true), true)
+			}},
+			{Kind: ClassMethod, Private: inc, Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				n, err := PrivateGet(thisObject(env), count)
+				if err != nil {
+					return Undefined(), err
+				}
+				return Undefined(), PrivateSet(thisObject(env), count, Number(n.AsNumber()+1))
+			}},
+			{Kind: ClassMethod, Key: StringKey("bump"), Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				m, err := PrivateGet(thisObject(env), inc)
+				if err != nil {
+					return Undefined(), err
+				}
+				if _, err := Call(m, thisObject(env).Value()); err != nil {
+					return Undefined(), err
+				}
+				return PrivateGet(thisObject(env), count)
+			}},
+			{Kind: ClassGetter, Key: StringKey("count"), Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return PrivateGet(thisObject(env), count)
+			}},
+			{Kind: ClassMethod, Static: true, Key: StringKey("has"), Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				ok, err := PrivateIn(count, argument(args, 0))
+				return Bool(ok), err
+			}},
+		},
+	}
+	counter, err := r.DefineClass(def)
+	test.AssertEqual(t, err, nil)
+	return counter, count
+}
+
+func TestClass(t *testing.T) {
+	r := NewRealm()
+	counter, count := newCounterClass(t, r)
+	ready, _ := Get(counter, StringKey("ready"))
+	test.AssertEqual(t, ready.AsBool(), true)
+
+	o, err := Construct(counter, nil, nil)
+	test.AssertEqual(t, err, nil)
+	created, _ := Get(counter, StringKey("created"))
+	test.AssertEqual(t, created.AsNumber(), 1.0)
+	bump, _ := Get(o.AsObject(), StringKey("bump"))
+	v, _ := Call(bump, o)
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	v, _ = Get(o.AsObject(), StringKey("count"))
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+
+	// Methods are not enumerable and the constructor cannot be called
+	proto, _ := Get(counter, StringKey("prototype"))
+	desc, _ := proto.AsObject().GetOwnProperty(StringKey("bump"))
+	test.AssertEqual(t, desc.Enumerable, false)
+	_, err = Call(counter.Value(), Undefined())
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// Brand checks
+	has, _ := Get(counter, StringKey("has"))
+	v, _ = Call(has, counter.Value(), o)
+	test.AssertEqual(t, v.AsBool(), true)
+	v, _ = Call(has, counter.Value(), NewObject(proto.AsObject()).Value())
+	test.AssertEqual(t, v.AsBool(), false)
+	_, err = Call(has, counter.Value(), Number(1))
+	test.AssertEqual(t, errorType(err), TypeError)
+	_, err = Call(bump, NewObject(proto.AsObject()).Value())
+	test.AssertEqual(t, errorType(err), TypeError)
+	test.AssertEqual(t, errorType(PrivateFieldAdd(o.AsObject(), count, Null())), TypeError)
+
+	// Another evaluation of the class has distinct private names
+	other, _ := newCounterClass(t, r)
+	has, _ = Get(other, StringKey("has"))
+	v, _ = Call(has, other.Value(), o)
+	test.AssertEqual(t, v.AsBool(), false)
+}
+
+func TestDerivedClass(t *testing.T) {
+	r := NewRealm()
+	counter, _ := newCounterClass(t, r)
+	secret := NewPrivateName("#secret")
+	var superCalls int
+
+	// class Sub extends Counter {
+	// 	label = "sub"; #secret;
+	// 	constructor() { this; super(); ...; super(); }
+	// }
+	sub, err := r.DefineClass(&ClassDefinition{
+		Name:        "Sub",
+		Env:         r.GlobalEnv,
+		HasHeritage: true,
+		Heritage:    counter.Value(),
+		Constructor: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+			_, err := ResolveThisBinding(env)
+			test.AssertEqual(t, errorType(err), ReferenceError)
+			if _, err := SuperCall(env, nil); err != nil {
+				return Undefined(), err
+			}
+			label, _ := Get(thisObject(env), StringKey("label"))
+			test.AssertEqual(t, label.String(), "sub")
+			superCalls++
+			if superCalls > 1 {
+				_, err := SuperCall(env, nil)
+				return Undefined(), err
+			}
+			return Undefined(), nil
+		},
+		Elements: []ClassElement{
+			{Kind: ClassField, Key: StringKey("label"), Body: func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+				return Str("sub"), nil
+			}},
+			{Kind: ClassField, Private: secret},
+		},
+	})
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, sub.GetPrototypeOf(), counter)
+
+	o, err := Construct(sub, nil, nil)
+	test.AssertEqual(t, err, nil)
+	v, _ := Get(o.AsObject(), StringKey("count"))
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	v, _ = PrivateGet(o.AsObject(), secret)
+	test.AssertEqual(t, v.IsUndefined(), true)
+	ok, _ := InstanceofOperator(o, counter.Value())
+	test.AssertEqual(t, ok, true)
+
+	_, err = Construct(sub, nil, nil)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+
+	_, err = r.DefineClass(&ClassDefinition{Env: r.GlobalEnv, HasHeritage: true, Heritage: Number(1)})
+	test.AssertEqual(t, errorType(err), TypeError)
+	orphan, _ := r.DefineClass(&ClassDefinition{Env: r.GlobalEnv, HasHeritage: true, Heritage: Null()})
+	proto, _ := Get(orphan, StringKey("prototype"))
+	test.AssertEqual(t, proto.AsObject().GetPrototypeOf() == nil, true)
+	_, err = Construct(orphan, nil, nil)
+	test.AssertEqual(t, errorType(err), TypeError)
+}
+
+func TestSubclassError(t *testing.T) {
+	r := NewRealm()
+	errorCtor, _ := Get(r.GlobalObject, StringKey("Error"))
+
+	// class MyError extends Error {}
+	myError, err := r.DefineClass(&ClassDefinition{Name: "MyError", Env: r.GlobalEnv, HasHeritage: true, Heritage: errorCtor})
+	test.AssertEqual(t, err, nil)
+	e, err := Construct(myError, []Value{Str("boom")}, nil)
+	test.AssertEqual(t, err, nil)
+	proto, _ := Get(myError, StringKey("prototype"))
+	test.AssertEqual(t, e.AsObject().GetPrototypeOf(), proto.AsObject())
+	ok, _ := InstanceofOperator(e, errorCtor)
+	test.AssertEqual(t, ok, true)
+
+	toString, _ := Get(e.AsObject(), StringKey("toString"))
+	s, _ := Call(toString, e)
+	test.AssertEqual(t, s.String(), "Error: boom")
+	CreateDataProperty(proto.AsObject(), StringKey("name"), Str("MyError"))
+	s, _ = Call(toString, e)
+	test.AssertEqual(t, s.String(), "MyError: boom")
+
+	// Calling Error without new still creates an error
+	e, _ = Call(errorCtor, Undefined())
+	test.AssertEqual(t, e.AsObject().GetPrototypeOf(), r.ErrorPrototype)
+
+	// Native errors inherit from Error, and so do their constructors
+	typeErrorCtor, _ := Get(r.GlobalObject, StringKey("TypeError"))
+	test.AssertEqual(t, typeErrorCtor.AsObject().GetPrototypeOf(), errorCtor.AsObject())
+	e, _ = Call(typeErrorCtor, Undefined(), Str("bad"))
+	s, _ = Call(toString, e)
+	test.AssertEqual(t, s.String(), "TypeError: bad")
+	test.AssertEqual(t, r.NewError(RangeError, "").GetPrototypeOf().GetPrototypeOf(), r.ErrorPrototype)
+}
@@ -0,0 +1,416 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Hint is the preferred type passed to ToPrimitive.
+type Hint uint8
+
+// Definition of ToPrimitive hints
+const (
+	HintDefault Hint = iota
+	HintString
+	HintNumber
+)
+
+var hintNames = [...]string{
+	HintDefault: "default",
+	HintString:  "string",
+	HintNumber:  "number",
+}
+
+// ToPrimitive converts v to a non-Object value, calling the object's
+// @@toPrimitive, valueOf or toString methods as needed.
+func ToPrimitive(v Value, hint Hint) (Value, error) {
+	o := v.AsObject()
+	if o == nil {
+		return v, nil
+	}
+
+	exoticToPrim, err := getMethod(o, SymbolKey(SymbolToPrimitive))
+	if err != nil {
+		return Undefined(), err
+	}
+	if !exoticToPrim.IsUndefined() {
+		result, err := Call(exoticToPrim, v, Str(hintNames[hint]))
+		if err != nil {
+			return Undefined(), err
+		}
+		if result.IsObject() {
+			return Undefined(), throwTypeError("Cannot convert object to primitive value")
+		}
+		return result, nil
+	}
+
+	if hint == HintDefault {
+		hint = HintNumber
+	}
+	return ordinaryToPrimitive(o, hint)
+}
+
+// ordinaryToPrimitive calls valueOf and toString in the order given
+// by hint and returns the first primitive result.
+func ordinaryToPrimitive(o *Object, hint Hint) (Value, error) {
+	methods := [2]string{"valueOf", "toString"}
+	if hint == HintString {
+		methods[0], methods[1] = methods[1], methods[0]
+	}
+
+	for _, name := range methods {
+		method, err := Get(o, StringKey(name))
+		if err != nil {
+			return Undefined(), err
+		}
+		if IsCallable(method) {
+			result, err := Call(method, o.Value())
+			if err != nil {
+				return Undefined(), err
+			}
+			if !result.IsObject() {
+				return result, nil
+			}
+		}
+	}
+	return Undefined(), throwTypeError("Cannot convert object to primitive value")
+}
+
+// ToBoolean converts v to a Boolean.
+func ToBoolean(v Value) bool {
+	switch v.kind {
+	case KindUndefined, KindNull:
+		return false
+	case KindBoolean:
+		return v.AsBool()
+	case KindNumber:
+		return v.num != 0 && !math.IsNaN(v.num)
+	case KindBigInt:
+		return v.AsBigInt().Sign() != 0
+	case KindString:
+		return v.AsString().Len() > 0
+	default:
+		return true
+	}
+}
+
+// ToNumeric converts v to a Number or a BigInt.
+func ToNumeric(v Value) (Value, error) {
+	prim, err := ToPrimitive(v, HintNumber)
+	if err != nil {
+		return Undefined(), err
+	}
+	if prim.kind == KindBigInt {
+		return prim, nil
+	}
+	f, err := ToNumber(prim)
+	return Number(f), err
+}
+
+// ToNumber converts v to a Number.
+func ToNumber(v Value) (float64, error) {
+	switch v.kind {
+	case KindUndefined:
+		return math.NaN(), nil
+	case KindNull:
+		return 0, nil
+	case KindBoolean, KindNumber:
+		return v.num, nil
+	case KindString:
+		return stringToNumber(v.AsString().String()), nil
+	case KindBigInt:
+		return 0, throwTypeError("Cannot convert a BigInt value to a number")
+	case KindSymbol:
+		return 0, throwTypeError("Cannot convert a Symbol value to a number")
+	}
+
+	prim, err := ToPrimitive(v, HintNumber)
+	if err != nil {
+		return 0, err
+	}
+	return ToNumber(prim)
+}
+
+// ToIntegerOrInfinity converts v to an integral Number, truncating
+// towards zero. NaN becomes 0 and infinities are kept.
+func ToIntegerOrInfinity(v Value) (float64, error) {
+	f, err := ToNumber(v)
+	if err != nil || math.IsNaN(f) {
+		return 0, err
+	}
+	return math.Trunc(f) + 0, nil
+}
+
+// ToLength converts v to an integer suitable as the length of an
+// array-like object, in the range [0, 2^53-1].
+func ToLength(v Value) (int64, error) {
+	f, err := ToIntegerOrInfinity(v)
+	if err != nil || f <= 0 {
+		return 0, err
+	}
+	return int64(math.Min(f, 1<<53-1)), nil
+}
+
+// ToString converts v to a String.
+func ToString(v Value) (*String, error) {
+	switch v.kind {
+	case KindString:
+		return v.AsString(), nil
+	case KindSymbol:
+		return nil, throwTypeError("Cannot convert a Symbol value to a string")
+	case KindObject:
+		prim, err := ToPrimitive(v, HintString)
+		if err != nil {
+			return nil, err
+		}
+		return ToString(prim)
+	case KindBigInt:
+		return NewString(v.AsBigInt().String()), nil
+	default:
+		return NewString(v.String()), nil
+	}
+}
+
+// ToPropertyKey converts v to a property key.
+func ToPropertyKey(v Value) (PropertyKey, error) {
+	key, err := ToPrimitive(v, HintString)
+	if err != nil {
+		return PropertyKey{}, err
+	}
+	if s := key.AsSymbol(); s != nil {
+		return SymbolKey(s), nil
+	}
+	str, err := ToString(key)
+	if err != nil {
+		return PropertyKey{}, err
+	}
+	return KeyOf(str), nil
+}
+
+// IsLooselyEqual implements the == operator.
+func IsLooselyEqual(x, y Value) (bool, error) {
+	if x.kind == y.kind {
+		return IsStrictlyEqual(x, y), nil
+	}
+	if x.IsNullish() && y.IsNullish() {
+		return true, nil
+	}
+
+	switch {
+	case x.kind == KindNumber && y.kind == KindString:
+		return x.num == stringToNumber(y.AsString().String()), nil
+	case x.kind == KindString && y.kind == KindNumber:
+		return IsLooselyEqual(y, x)
+
+	case x.kind == KindBigInt && y.kind == KindString:
+		n, ok := stringToBigInt(y.AsString().String())
+		return ok && x.AsBigInt().Cmp(n) == 0, nil
+	case x.kind == KindString && y.kind == KindBigInt:
+		return IsLooselyEqual(y, x)
+
+	case x.kind == KindBoolean:
+		return IsLooselyEqual(Number(x.num), y)
+	case y.kind == KindBoolean:
+		return IsLooselyEqual(x, Number(y.num))
+
+	case x.kind >= KindNumber && x.kind <= KindSymbol && y.kind == KindObject:
+		prim, err := ToPrimitive(y, HintDefault)
+		if err != nil {
+			return false, err
+		}
+		return IsLooselyEqual(x, prim)
+	case x.kind == KindObject && y.kind >= KindNumber && y.kind <= KindSymbol:
+		return IsLooselyEqual(y, x)
+
+	case x.kind == KindBigInt && y.kind == KindNumber:
+		return compareBigIntNumber(x.AsBigInt(), y.num) == 0, nil
+	case x.kind == KindNumber && y.kind == KindBigInt:
+		return compareBigIntNumber(y.AsBigInt(), x.num) == 0, nil
+	}
+	return false, nil
+}
+
+// compareBigIntNumber compares a BigInt and a Number mathematically and
+// returns -1, 0 or +1. It returns 2 if f is NaN.
+func compareBigIntNumber(n *big.Int, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 2
+	case math.IsInf(f, 1):
+		return -1
+	case math.IsInf(f, -1):
+		return 1
+	}
+	return new(big.Float).SetInt(n).Cmp(big.NewFloat(f))
+}
+
+// numberToString implements Number::toString with radix 10.
+func numberToString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f == 0:
+		return "0"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f < 0:
+		return "-" + numberToString(-f)
+	}
+
+	// Shortest digits that round-trip, as "d.ddde±x"
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, exp := e[:strings.IndexByte(e, 'e')], e[strings.IndexByte(e, 'e')+1:]
+	digits := strings.Replace(mant, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1
+
+	switch {
+	case k <= n && n <= 21:
+		return digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return "0." + strings.Repeat("0", -n) + digits
+	}
+
+	sign := "+"
+	if n-1 < 0 {
+		sign = "-"
+	}
+	exp = sign + strconv.Itoa(abs(n-1))
+	if k == 1 {
+		return digits + "e" + exp
+	}
+	return digits[:1] + "." + digits[1:] + "e" + exp
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// isStrWhiteSpace reports whether r is a WhiteSpace or LineTerminator
+// code point, as trimmed by StringToNumber.
+func isStrWhiteSpace(r rune) bool {
+	switch r {
+	case '\t', '\v', '\f', ' ', '\u00A0', '\uFEFF', '\n', '\r', '\u2028', '\u2029':
+		return true
+	}
+	return unicode.Is(unicode.Zs, r)
+}
+
+// stringToNumber implements StringToNumber: it parses a
+// StringNumericLiteral and returns NaN if s is not one.
+func stringToNumber(s string) float64 {
+	s = strings.TrimFunc(s, isStrWhiteSpace)
+	if s == "" {
+		return 0
+	}
+
+	if n, ok := nonDecimalIntegerLiteral(s); ok {
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f
+	}
+
+	unsigned := strings.TrimLeft(s, "+-")
+	if len(s)-len(unsigned) > 1 {
+		return math.NaN()
+	}
+	if unsigned == "Infinity" {
+		if s[0] == '-' {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+	if !isStrUnsignedDecimalLiteral(unsigned) {
+		return math.NaN()
+	}
+
+	// ParseFloat rounds correctly and returns ±Inf or ±0 with a range
+	// error on overflow or underflow, which is the expected result.
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// stringToBigInt implements StringToBigInt. It reports false if s is
+// not a StringIntegerLiteral.
+func stringToBigInt(s string) (*big.Int, bool) {
+	s = strings.TrimFunc(s, isStrWhiteSpace)
+	if s == "" {
+		return new(big.Int), true
+	}
+	if n, ok := nonDecimalIntegerLiteral(s); ok {
+		return n, true
+	}
+
+	digits := s
+	if s[0] == '+' || s[0] == '-' {
+		digits = s[1:]
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, false
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// nonDecimalIntegerLiteral parses a binary, octal or hexadecimal
+// integer literal such as 0x1F. Signs and separators are not allowed.
+func nonDecimalIntegerLiteral(s string) (*big.Int, bool) {
+	if len(s) < 3 || s[0] != '0' {
+		return nil, false
+	}
+	base := 0
+	switch s[1] {
+	case 'b', 'B':
+		base = 2
+	case 'o', 'O':
+		base = 8
+	case 'x', 'X':
+		base = 16
+	default:
+		return nil, false
+	}
+	if strings.ContainsAny(s[2:], "+-_") {
+		return nil, false
+	}
+	return new(big.Int).SetString(s[2:], base)
+}
+
+// isStrUnsignedDecimalLiteral reports whether s matches
+// StrUnsignedDecimalLiteral, excluding Infinity.
+func isStrUnsignedDecimalLiteral(s string) bool {
+	digits := func() int {
+		n := 0
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		s = s[n:]
+		return n
+	}
+
+	n := digits()
+	if s != "" && s[0] == '.' {
+		s = s[1:]
+		n += digits()
+	}
+	if n == 0 {
+		return false
+	}
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return s == ""
+}
@@ -0,0 +1,211 @@
+package runtime
+
+// DescriptorField is a set of fields present in a PropertyDescriptor.
+type DescriptorField uint8
+
+// Definition of property descriptor fields
+const (
+	HasValue DescriptorField = 1 << iota
+	HasWritable
+	HasGet
+	HasSet
+	HasEnumerable
+	HasConfigurable
+)
+
+// PropertyDescriptor describes the attributes of a property. Fields
+// records which attributes are present; absent fields are ignored.
+type PropertyDescriptor struct {
+	Value        Value
+	Get          Value // getter function or undefined
+	Set          Value // setter function or undefined
+	Writable     bool
+	Enumerable   bool
+	Configurable bool
+	Fields       DescriptorField
+}
+
+// DataDescriptor returns a complete data property descriptor.
+func DataDescriptor(v Value, writable, enumerable, configurable bool) PropertyDescriptor {
+	return PropertyDescriptor{
+		Value:        v,
+		Writable:     writable,
+		Enumerable:   enumerable,
+		Configurable: configurable,
+		Fields:       HasValue | HasWritable | HasEnumerable | HasConfigurable,
+	}
+}
+
+// AccessorDescriptor returns a complete accessor property descriptor.
+// get and set must be functions or undefined.
+func AccessorDescriptor(get, set Value, enumerable, configurable bool) PropertyDescriptor {
+	return PropertyDescriptor{
+		Get:          get,
+		Set:          set,
+		Enumerable:   enumerable,
+		Configurable: configurable,
+		Fields:       HasGet | HasSet | HasEnumerable | HasConfigurable,
+	}
+}
+
+// Has reports whether all the fields f are present in d.
+func (d PropertyDescriptor) Has(f DescriptorField) bool {
+	return d.Fields&f == f
+}
+
+// IsAccessorDescriptor reports whether d has a [[Get]] or [[Set]] field.
+func (d PropertyDescriptor) IsAccessorDescriptor() bool {
+	return d.Fields&(HasGet|HasSet) != 0
+}
+
+// IsDataDescriptor reports whether d has a [[Value]] or [[Writable]] field.
+func (d PropertyDescriptor) IsDataDescriptor() bool {
+	return d.Fields&(HasValue|HasWritable) != 0
+}
+
+// IsGenericDescriptor reports whether d is neither an accessor nor a
+// data descriptor.
+func (d PropertyDescriptor) IsGenericDescriptor() bool {
+	return !d.IsAccessorDescriptor() && !d.IsDataDescriptor()
+}
+
+// attributes are the boolean attributes of a stored property.
+type attributes uint8
+
+const (
+	attrWritable attributes = 1 << iota
+	attrEnumerable
+	attrConfigurable
+	attrAccessor
+)
+
+// accessor holds the getter and setter of an accessor property in the
+// slot of the property.
+type accessor struct {
+	get, set Value
+}
+
+// attributesOf returns the attributes of the complete descriptor d.
+func attributesOf(d PropertyDescriptor) attributes {
+	var attrs attributes
+	if d.IsAccessorDescriptor() {
+		attrs |= attrAccessor
+	} else if d.Writable {
+		attrs |= attrWritable
+	}
+	if d.Enumerable {
+		attrs |= attrEnumerable
+	}
+	if d.Configurable {
+		attrs |= attrConfigurable
+	}
+	return attrs
+}
+
+// slotValue returns what is stored in the slot of a property described
+// by the complete descriptor d.
+func slotValue(d PropertyDescriptor) Value {
+	if d.IsAccessorDescriptor() {
+		return Value{ref: &accessor{d.Get, d.Set}}
+	}
+	return d.Value
+}
+
+// storedDescriptor returns the complete descriptor of a stored property.
+func storedDescriptor(attrs attributes, slot Value) PropertyDescriptor {
+	enumerable, configurable := attrs&attrEnumerable != 0, attrs&attrConfigurable != 0
+	if attrs&attrAccessor != 0 {
+		a := slot.ref.(*accessor)
+		return AccessorDescriptor(a.get, a.set, enumerable, configurable)
+	}
+	return DataDescriptor(slot, attrs&attrWritable != 0, enumerable, configurable)
+}
+
+// merge returns the complete descriptor obtained by applying every
+// field present in d to the complete descriptor current. Converting
+// between data and accessor properties keeps only the shared attributes.
+func (current PropertyDescriptor) merge(d PropertyDescriptor) PropertyDescriptor {
+	switch {
+	case d.IsAccessorDescriptor() && !current.IsAccessorDescriptor():
+		current = AccessorDescriptor(Undefined(), Undefined(), current.Enumerable, current.Configurable)
+	case d.IsDataDescriptor() && current.IsAccessorDescriptor():
+		current = DataDescriptor(Undefined(), false, current.Enumerable, current.Configurable)
+	}
+
+	if d.Has(HasValue) {
+		current.Value = d.Value
+	}
+	if d.Has(HasWritable) {
+		current.Writable = d.Writable
+	}
+	if d.Has(HasGet) {
+		current.Get = d.Get
+	}
+	if d.Has(HasSet) {
+		current.Set = d.Set
+	}
+	if d.Has(HasEnumerable) {
+		current.Enumerable = d.Enumerable
+	}
+	if d.Has(HasConfigurable) {
+		current.Configurable = d.Configurable
+	}
+	return current
+}
+
+// validateAndApplyPropertyDescriptor implements
+// ValidateAndApplyPropertyDescriptor. When o is nil it only validates.
+// exists is false if the property does not exist yet.
+func validateAndApplyPropertyDescriptor(o *Object, key PropertyKey, extensible bool, d, current PropertyDescriptor, exists bool) bool {
+	if !exists {
+		if !extensible {
+			return false
+		}
+		if o != nil {
+			var initial PropertyDescriptor
+			if d.IsAccessorDescriptor() {
+				initial = AccessorDescriptor(Undefined(), Undefined(), false, false)
+			} else {
+				initial = DataDescriptor(Undefined(), false, false, false)
+			}
+			o.storeProperty(key, initial.merge(d), false)
+		}
+		return true
+	}
+
+	if d.Fields == 0 {
+		return true
+	}
+
+	if !current.Configurable {
+		if d.Has(HasConfigurable) && d.Configurable {
+			return false
+		}
+		if d.Has(HasEnumerable) && d.Enumerable != current.Enumerable {
+			return false
+		}
+		if !d.IsGenericDescriptor() && d.IsAccessorDescriptor() != current.IsAccessorDescriptor() {
+			return false
+		}
+		if current.IsAccessorDescriptor() {
+			if d.Has(HasGet) && !SameValue(d.Get, current.Get) {
+				return false
+			}
+			if d.Has(HasSet) && !SameValue(d.Set, current.Set) {
+				return false
+			}
+		} else if !current.Writable {
+			if d.Has(HasWritable) && d.Writable {
+				return false
+			}
+			if d.Has(HasValue) && !SameValue(d.Value, current.Value) {
+				return false
+			}
+		}
+	}
+
+	if o != nil {
+		o.storeProperty(key, current.merge(d), true)
+	}
+	return true
+}
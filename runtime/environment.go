@@ -0,0 +1,687 @@
+package runtime
+
+// Environment is an Environment Record: it associates identifiers with
+// bindings within a scope. Each environment has an outer environment,
+// which is nil for the global environment.
+type Environment interface {
+	Outer() Environment
+	HasBinding(name string) (bool, error)
+	CreateMutableBinding(name string, deletable bool) error
+	CreateImmutableBinding(name string, strict bool) error
+	InitializeBinding(name string, v Value) error
+	SetMutableBinding(name string, v Value, strict bool) error
+	GetBindingValue(name string, strict bool) (Value, error)
+	DeleteBinding(name string) (bool, error)
+	HasThisBinding() bool
+	HasSuperBinding() bool
+	WithBaseObject() Value
+}
+
+// binding is a binding of a declarative environment.
+type binding struct {
+	value       Value
+	initialized bool
+	mutable     bool
+	strict      bool // assignments to an immutable binding always throw
+	deletable   bool
+	indirect    *ModuleEnvironment // for import bindings: the target module
+	target      string             // for import bindings: the target name
+}
+
+// DeclarativeEnvironment holds the bindings created by declarations,
+// such as var, let, const, class, module, import and function
+// declarations, within its scope.
+type DeclarativeEnvironment struct {
+	outer    Environment
+	bindings map[string]*binding
+}
+
+// NewDeclarativeEnvironment creates an empty declarative environment.
+func NewDeclarativeEnvironment(outer Environment) *DeclarativeEnvironment {
+	return &DeclarativeEnvironment{outer: outer, bindings: make(map[string]*binding)}
+}
+
+// Outer returns the outer environment.
+func (e *DeclarativeEnvironment) Outer() Environment {
+	return e.outer
+}
+
+// HasBinding reports whether e has a binding for name.
+func (e *DeclarativeEnvironment) HasBinding(name string) (bool, error) {
+	_, ok := e.bindings[name]
+	return ok, nil
+}
+
+// CreateMutableBinding creates an uninitialized mutable binding.
+func (e *DeclarativeEnvironment) CreateMutableBinding(name string, deletable bool) error {
+	e.bindings[name] = &binding{mutable: true, deletable: deletable}
+	return nil
+}
+
+// CreateImmutableBinding creates an uninitialized immutable binding.
+// Assignments to strict bindings always throw a TypeError.
+func (e *DeclarativeEnvironment) CreateImmutableBinding(name string, strict bool) error {
+	e.bindings[name] = &binding{strict: strict}
+	return nil
+}
+
+// InitializeBinding sets the initial value of a binding, ending its
+// temporal dead zone.
+func (e *DeclarativeEnvironment) InitializeBinding(name string, v Value) error {
+	b, ok := e.bindings[name]
+	if !ok {
+		return throwError(ReferenceError, "%s is not defined", name)
+	}
+	b.value = v
+	b.initialized = true
+	return nil
+}
+
+// SetMutableBinding assigns v to the binding for name.
+func (e *DeclarativeEnvironment) SetMutableBinding(name string, v Value, strict bool) error {
+	b, ok := e.bindings[name]
+	if !ok {
+		if strict {
+			return throwError(ReferenceError, "%s is not defined", name)
+		}
+		e.CreateMutableBinding(name, true)
+		return e.InitializeBinding(name, v)
+	}
+
+	if b.strict {
+		strict = true
+	}
+	switch {
+	case !b.initialized:
+		return throwError(ReferenceError, "Cannot access '%s' before initialization", name)
+	case b.mutable:
+		b.value = v
+	case strict:
+		return throwTypeError("Assignment to constant variable '%s'", name)
+	}
+	return nil
+}
+
+// GetBindingValue returns the value of the binding for name.
+func (e *DeclarativeEnvironment) GetBindingValue(name string, strict bool) (Value, error) {
+	b, ok := e.bindings[name]
+	if !ok {
+		return Undefined(), throwError(ReferenceError, "%s is not defined", name)
+	}
+	if b.indirect != nil {
+		return b.indirect.GetBindingValue(b.target, true)
+	}
+	if !b.initialized {
+		return Undefined(), throwError(ReferenceError, "Cannot access '%s' before initialization", name)
+	}
+	return b.value, nil
+}
+
+// DeleteBinding deletes the binding for name if it is deletable.
+func (e *DeclarativeEnvironment) DeleteBinding(name string) (bool, error) {
+	b, ok := e.bindings[name]
+	if !ok {
+		return false, throwError(ReferenceError, "%s is not defined", name)
+	}
+	if !b.deletable {
+		return false, nil
+	}
+	delete(e.bindings, name)
+	return true, nil
+}
+
+// HasThisBinding reports false: declarative environments do not bind this.
+func (e *DeclarativeEnvironment) HasThisBinding() bool {
+	return false
+}
+
+// HasSuperBinding reports false: declarative environments do not bind super.
+func (e *DeclarativeEnvironment) HasSuperBinding() bool {
+	return false
+}
+
+// WithBaseObject returns undefined.
+func (e *DeclarativeEnvironment) WithBaseObject() Value {
+	return Undefined()
+}
+
+// CreatePerIterationEnvironment copies the bindings names of the
+// environment of the previous iteration of a for (let ...) loop into a
+// fresh environment, so that closures created in each iteration
+// capture a distinct binding.
+func CreatePerIterationEnvironment(last *DeclarativeEnvironment, names []string) (*DeclarativeEnvironment, error) {
+	if len(names) == 0 {
+		return last, nil
+	}
+	env := NewDeclarativeEnvironment(last.outer)
+	for _, name := range names {
+		env.CreateMutableBinding(name, false)
+		v, err := last.GetBindingValue(name, true)
+		if err != nil {
+			return nil, err
+		}
+		env.InitializeBinding(name, v)
+	}
+	return env, nil
+}
+
+// ObjectEnvironment binds the string-keyed properties of an object, as
+// the global object or the object of a with statement.
+type ObjectEnvironment struct {
+	outer  Environment
+	object *Object
+	with   bool // created by a with statement
+}
+
+// NewObjectEnvironment creates an environment binding the properties of
+// o. with is true for the environments of with statements.
+func NewObjectEnvironment(o *Object, with bool, outer Environment) *ObjectEnvironment {
+	return &ObjectEnvironment{outer: outer, object: o, with: with}
+}
+
+// Outer returns the outer environment.
+func (e *ObjectEnvironment) Outer() Environment {
+	return e.outer
+}
+
+// BindingObject returns the object whose properties are bound.
+func (e *ObjectEnvironment) BindingObject() *Object {
+	return e.object
+}
+
+// HasBinding reports whether the binding object has a property name
+// that is not blocked by @@unscopables.
+func (e *ObjectEnvironment) HasBinding(name string) (bool, error) {
+	key := StringKey(name)
+	if !e.object.HasProperty(key) {
+		return false, nil
+	}
+	if !e.with {
+		return true, nil
+	}
+
+	unscopables, err := Get(e.object, SymbolKey(SymbolUnscopables))
+	if err != nil {
+		return false, err
+	}
+	if u := unscopables.AsObject(); u != nil {
+		blocked, err := Get(u, key)
+		if err != nil {
+			return false, err
+		}
+		return !ToBoolean(blocked), nil
+	}
+	return true, nil
+}
+
+// CreateMutableBinding defines a property initialized to undefined.
+func (e *ObjectEnvironment) CreateMutableBinding(name string, deletable bool) error {
+	return DefinePropertyOrThrow(e.object, StringKey(name), DataDescriptor(Undefined(), true, true, deletable))
+}
+
+// CreateImmutableBinding is never used on object environments.
+func (e *ObjectEnvironment) CreateImmutableBinding(name string, strict bool) error {
+	panic("runtime: immutable binding in object environment")
+}
+
+// InitializeBinding sets the value of the property name.
+func (e *ObjectEnvironment) InitializeBinding(name string, v Value) error {
+	return e.SetMutableBinding(name, v, false)
+}
+
+// SetMutableBinding sets the value of the property name.
+func (e *ObjectEnvironment) SetMutableBinding(name string, v Value, strict bool) error {
+	key := StringKey(name)
+	if !e.object.HasProperty(key) && strict {
+		return throwError(ReferenceError, "%s is not defined", name)
+	}
+	return Set(e.object, key, v, strict)
+}
+
+// GetBindingValue returns the value of the property name.
+func (e *ObjectEnvironment) GetBindingValue(name string, strict bool) (Value, error) {
+	key := StringKey(name)
+	if !e.object.HasProperty(key) {
+		if strict {
+			return Undefined(), throwError(ReferenceError, "%s is not defined", name)
+		}
+		return Undefined(), nil
+	}
+	return Get(e.object, key)
+}
+
+// DeleteBinding deletes the property name.
+func (e *ObjectEnvironment) DeleteBinding(name string) (bool, error) {
+	return e.object.Delete(StringKey(name)), nil
+}
+
+// HasThisBinding reports false: object environments do not bind this.
+func (e *ObjectEnvironment) HasThisBinding() bool {
+	return false
+}
+
+// HasSuperBinding reports false: object environments do not bind super.
+func (e *ObjectEnvironment) HasSuperBinding() bool {
+	return false
+}
+
+// WithBaseObject returns the binding object of with environments, which
+// becomes the this value of functions called through the binding.
+func (e *ObjectEnvironment) WithBaseObject() Value {
+	if e.with {
+		return e.object.Value()
+	}
+	return Undefined()
+}
+
+// ThisBindingStatus is the state of the this binding of a function
+// environment.
+type ThisBindingStatus uint8
+
+// Definition of this binding states
+const (
+	ThisLexical ThisBindingStatus = iota // arrow functions
+	ThisInitialized
+	ThisUninitialized // derived constructors before super()
+)
+
+// FunctionEnvironment is the top-level environment of a function call.
+// It also binds this and, for methods, the home object used by super.
+type FunctionEnvironment struct {
+	DeclarativeEnvironment
+	thisValue  Value
+	thisStatus ThisBindingStatus
+	function   *Object
+	newTarget  Value
+}
+
+// NewFunctionEnvironment creates the environment of a call to f. Arrow
+// functions use ThisLexical; derived constructors start ThisUninitialized.
+func NewFunctionEnvironment(f *Object, status ThisBindingStatus, newTarget Value, outer Environment) *FunctionEnvironment {
+	return &FunctionEnvironment{
+		DeclarativeEnvironment: *NewDeclarativeEnvironment(outer),
+		thisStatus:             status,
+		function:               f,
+		newTarget:              newTarget,
+	}
+}
+
+// FunctionObject returns the function whose call created e.
+func (e *FunctionEnvironment) FunctionObject() *Object {
+	return e.function
+}
+
+// NewTarget returns the value of new.target, undefined for calls.
+func (e *FunctionEnvironment) NewTarget() Value {
+	return e.newTarget
+}
+
+// HasThisBinding reports whether e binds this, which all but arrow
+// function environments do.
+func (e *FunctionEnvironment) HasThisBinding() bool {
+	return e.thisStatus != ThisLexical
+}
+
+// HasSuperBinding reports whether super can be used in e, which is
+// the case in methods.
+func (e *FunctionEnvironment) HasSuperBinding() bool {
+	return e.thisStatus != ThisLexical && e.homeObject() != nil
+}
+
+// homeObject returns the home object of the function, or nil.
+func (e *FunctionEnvironment) homeObject() *Object {
+	if e.function == nil || e.function.function() == nil {
+		return nil
+	}
+	return e.function.function().homeObject
+}
+
+// GetSuperBase returns the object super property lookups start from:
+// the prototype of the home object of the method.
+func (e *FunctionEnvironment) GetSuperBase() *Object {
+	home := e.homeObject()
+	if home == nil {
+		return nil
+	}
+	return home.GetPrototypeOf()
+}
+
+// BindThisValue initializes the this binding.
+func (e *FunctionEnvironment) BindThisValue(v Value) error {
+	if e.thisStatus == ThisInitialized {
+		return throwError(ReferenceError, "Super constructor may only be called once")
+	}
+	e.thisValue = v
+	e.thisStatus = ThisInitialized
+	return nil
+}
+
+// GetThisBinding returns the this value.
+func (e *FunctionEnvironment) GetThisBinding() (Value, error) {
+	if e.thisStatus == ThisUninitialized {
+		return Undefined(), throwError(ReferenceError, "Must call super constructor in derived class before accessing 'this'")
+	}
+	return e.thisValue, nil
+}
+
+// GlobalEnvironment is the outermost environment. It combines an object
+// environment over the global object, holding var and function
+// declarations, with a declarative environment for lexical declarations.
+type GlobalEnvironment struct {
+	object      *ObjectEnvironment
+	declarative *DeclarativeEnvironment
+	thisValue   *Object
+	varNames    map[string]bool
+}
+
+// NewGlobalEnvironment creates a global environment over the global
+// object g. thisValue is the value of this at the top level, usually g.
+func NewGlobalEnvironment(g, thisValue *Object) *GlobalEnvironment {
+	return &GlobalEnvironment{
+		object:      NewObjectEnvironment(g, false, nil),
+		declarative: NewDeclarativeEnvironment(nil),
+		thisValue:   thisValue,
+		varNames:    make(map[string]bool),
+	}
+}
+
+// Outer returns nil.
+func (e *GlobalEnvironment) Outer() Environment {
+	return nil
+}
+
+// GlobalObject returns the global object.
+func (e *GlobalEnvironment) GlobalObject() *Object {
+	return e.object.object
+}
+
+// HasBinding reports whether name is declared lexically or is a
+// property of the global object.
+func (e *GlobalEnvironment) HasBinding(name string) (bool, error) {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return true, nil
+	}
+	return e.object.HasBinding(name)
+}
+
+// CreateMutableBinding creates a lexical binding.
+func (e *GlobalEnvironment) CreateMutableBinding(name string, deletable bool) error {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return throwTypeError("Identifier '%s' has already been declared", name)
+	}
+	return e.declarative.CreateMutableBinding(name, deletable)
+}
+
+// CreateImmutableBinding creates a lexical constant binding.
+func (e *GlobalEnvironment) CreateImmutableBinding(name string, strict bool) error {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return throwTypeError("Identifier '%s' has already been declared", name)
+	}
+	return e.declarative.CreateImmutableBinding(name, strict)
+}
+
+// InitializeBinding initializes a lexical binding or global property.
+func (e *GlobalEnvironment) InitializeBinding(name string, v Value) error {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return e.declarative.InitializeBinding(name, v)
+	}
+	return e.object.InitializeBinding(name, v)
+}
+
+// SetMutableBinding assigns to a lexical binding or global property.
+func (e *GlobalEnvironment) SetMutableBinding(name string, v Value, strict bool) error {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return e.declarative.SetMutableBinding(name, v, strict)
+	}
+	return e.object.SetMutableBinding(name, v, strict)
+}
+
+// GetBindingValue reads a lexical binding or global property.
+func (e *GlobalEnvironment) GetBindingValue(name string, strict bool) (Value, error) {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return e.declarative.GetBindingValue(name, strict)
+	}
+	return e.object.GetBindingValue(name, strict)
+}
+
+// DeleteBinding deletes a lexical binding or global property.
+func (e *GlobalEnvironment) DeleteBinding(name string) (bool, error) {
+	if ok, _ := e.declarative.HasBinding(name); ok {
+		return e.declarative.DeleteBinding(name)
+	}
+	if HasOwnProperty(e.GlobalObject(), StringKey(name)) {
+		ok, err := e.object.DeleteBinding(name)
+		if ok {
+			delete(e.varNames, name)
+		}
+		return ok, err
+	}
+	return true, nil
+}
+
+// HasThisBinding reports true.
+func (e *GlobalEnvironment) HasThisBinding() bool {
+	return true
+}
+
+// HasSuperBinding reports false.
+func (e *GlobalEnvironment) HasSuperBinding() bool {
+	return false
+}
+
+// WithBaseObject returns undefined.
+func (e *GlobalEnvironment) WithBaseObject() Value {
+	return Undefined()
+}
+
+// GetThisBinding returns the global this value.
+func (e *GlobalEnvironment) GetThisBinding() (Value, error) {
+	return e.thisValue.Value(), nil
+}
+
+// HasVarDeclaration reports whether name was declared by var or function.
+func (e *GlobalEnvironment) HasVarDeclaration(name string) bool {
+	return e.varNames[name]
+}
+
+// HasLexicalDeclaration reports whether name was declared by let,
+// const or class.
+func (e *GlobalEnvironment) HasLexicalDeclaration(name string) bool {
+	ok, _ := e.declarative.HasBinding(name)
+	return ok
+}
+
+// HasRestrictedGlobalProperty reports whether name is a
+// non-configurable property of the global object, such as undefined,
+// which lexical declarations may not shadow.
+func (e *GlobalEnvironment) HasRestrictedGlobalProperty(name string) bool {
+	desc, ok := e.GlobalObject().GetOwnProperty(StringKey(name))
+	return ok && !desc.Configurable
+}
+
+// CanDeclareGlobalVar reports whether a var declaration of name can
+// create a global property.
+func (e *GlobalEnvironment) CanDeclareGlobalVar(name string) bool {
+	return HasOwnProperty(e.GlobalObject(), StringKey(name)) || e.GlobalObject().IsExtensible()
+}
+
+// CanDeclareGlobalFunction reports whether a function declaration of
+// name can create or replace a global property.
+func (e *GlobalEnvironment) CanDeclareGlobalFunction(name string) bool {
+	desc, ok := e.GlobalObject().GetOwnProperty(StringKey(name))
+	if !ok {
+		return e.GlobalObject().IsExtensible()
+	}
+	return desc.Configurable || desc.IsDataDescriptor() && desc.Writable && desc.Enumerable
+}
+
+// CreateGlobalVarBinding creates the global property of a var declaration.
+func (e *GlobalEnvironment) CreateGlobalVarBinding(name string, deletable bool) error {
+	g := e.GlobalObject()
+	if !HasOwnProperty(g, StringKey(name)) && g.IsExtensible() {
+		if err := e.object.CreateMutableBinding(name, deletable); err != nil {
+			return err
+		}
+		if err := e.object.InitializeBinding(name, Undefined()); err != nil {
+			return err
+		}
+	}
+	e.varNames[name] = true
+	return nil
+}
+
+// CreateGlobalFunctionBinding creates or replaces the global property of
+// a function declaration.
+func (e *GlobalEnvironment) CreateGlobalFunctionBinding(name string, v Value, deletable bool) error {
+	g := e.GlobalObject()
+	key := StringKey(name)
+	desc := DataDescriptor(v, true, true, deletable)
+	if existing, ok := g.GetOwnProperty(key); ok && !existing.Configurable {
+		desc = PropertyDescriptor{Value: v, Fields: HasValue}
+	}
+	if err := DefinePropertyOrThrow(g, key, desc); err != nil {
+		return err
+	}
+	if err := Set(g, key, v, false); err != nil {
+		return err
+	}
+	e.varNames[name] = true
+	return nil
+}
+
+// ModuleEnvironment is the top-level environment of a module. Its
+// import bindings are indirect references to bindings of other modules.
+type ModuleEnvironment struct {
+	DeclarativeEnvironment
+}
+
+// NewModuleEnvironment creates the environment of a module.
+func NewModuleEnvironment(outer Environment) *ModuleEnvironment {
+	return &ModuleEnvironment{*NewDeclarativeEnvironment(outer)}
+}
+
+// CreateImportBinding creates an immutable binding for name that
+// reads the binding target of the environment of the module m.
+func (e *ModuleEnvironment) CreateImportBinding(name string, m *ModuleEnvironment, target string) error {
+	e.bindings[name] = &binding{initialized: true, strict: true, indirect: m, target: target}
+	return nil
+}
+
+// GetBindingValue returns the value of a binding. Module code is always
+// strict, and indirect bindings read through to the exporting module.
+func (e *ModuleEnvironment) GetBindingValue(name string, strict bool) (Value, error) {
+	if b := e.bindings[name]; b != nil && b.indirect != nil {
+		if ok, _ := b.indirect.HasBinding(b.target); !ok {
+			return Undefined(), throwError(ReferenceError, "%s is not defined", b.target)
+		}
+	}
+	return e.DeclarativeEnvironment.GetBindingValue(name, true)
+}
+
+// DeleteBinding is never used on module environments.
+func (e *ModuleEnvironment) DeleteBinding(name string) (bool, error) {
+	panic("runtime: delete of a module binding")
+}
+
+// HasThisBinding reports true: this is undefined in modules.
+func (e *ModuleEnvironment) HasThisBinding() bool {
+	return true
+}
+
+// GetThisBinding returns undefined.
+func (e *ModuleEnvironment) GetThisBinding() (Value, error) {
+	return Undefined(), nil
+}
+
+// GetThisEnvironment returns the nearest environment that binds this.
+func GetThisEnvironment(env Environment) Environment {
+	for !env.HasThisBinding() {
+		env = env.Outer()
+	}
+	return env
+}
+
+// ResolveThisBinding returns the value of this in env.
+func ResolveThisBinding(env Environment) (Value, error) {
+	switch e := GetThisEnvironment(env).(type) {
+	case *FunctionEnvironment:
+		return e.GetThisBinding()
+	case *GlobalEnvironment:
+		return e.GetThisBinding()
+	case *ModuleEnvironment:
+		return e.GetThisBinding()
+	}
+	panic("runtime: environment without this binding")
+}
+
+// Reference is a resolved identifier: the environment holding its
+// binding, or an unresolvable reference if no environment does.
+type Reference struct {
+	env    Environment // nil if unresolvable
+	global *GlobalEnvironment
+	name   string
+	strict bool
+}
+
+// ResolveBinding looks name up in env and its outer environments.
+func ResolveBinding(env Environment, name string, strict bool) (*Reference, error) {
+	ref := &Reference{name: name, strict: strict}
+	for ; env != nil; env = env.Outer() {
+		if g, ok := env.(*GlobalEnvironment); ok {
+			ref.global = g
+		}
+		ok, err := env.HasBinding(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ref.env = env
+			return ref, nil
+		}
+	}
+	return ref, nil
+}
+
+// IsUnresolvable reports whether no environment had a binding.
+func (r *Reference) IsUnresolvable() bool {
+	return r.env == nil
+}
+
+// Environment returns the environment holding the binding, or nil.
+func (r *Reference) Environment() Environment {
+	return r.env
+}
+
+// GetValue reads the referenced binding.
+func (r *Reference) GetValue() (Value, error) {
+	if r.env == nil {
+		return Undefined(), throwError(ReferenceError, "%s is not defined", r.name)
+	}
+	return r.env.GetBindingValue(r.name, r.strict)
+}
+
+// PutValue assigns v to the referenced binding. In sloppy mode,
+// assigning to an unresolvable reference creates a global property.
+func (r *Reference) PutValue(v Value) error {
+	if r.env == nil {
+		if r.strict || r.global == nil {
+			return throwError(ReferenceError, "%s is not defined", r.name)
+		}
+		return Set(r.global.GlobalObject(), StringKey(r.name), v, false)
+	}
+	return r.env.SetMutableBinding(r.name, v, r.strict)
+}
+
+// InitializeReferencedBinding initializes the referenced binding.
+func (r *Reference) InitializeReferencedBinding(v Value) error {
+	return r.env.InitializeBinding(r.name, v)
+}
+
+// ThisValue returns the this value for a call through the reference:
+// the binding object of a with statement, or undefined.
+func (r *Reference) ThisValue() Value {
+	if r.env == nil {
+		return Undefined()
+	}
+	return r.env.WithBaseObject()
+}
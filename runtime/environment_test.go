@@ -0,0 +1,235 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func errorType(err error) ErrorType {
+	if err == nil {
+		return ^ErrorType(0)
+	}
+	return err.(*Exception).Type
+}
+
+func TestDeclarativeEnvironment(t *testing.T) {
+	env := NewDeclarativeEnvironment(nil)
+
+	// let x; before the declaration is evaluated
+	env.CreateMutableBinding("x", false)
+	_, err := env.GetBindingValue("x", true)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+	test.AssertEqual(t, errorType(env.SetMutableBinding("x", Number(1), true)), ReferenceError)
+	env.InitializeBinding("x", Undefined())
+	test.AssertEqual(t, env.SetMutableBinding("x", Number(1), true), nil)
+	v, _ := env.GetBindingValue("x", true)
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+
+	// const y = 2; assignments throw even in sloppy mode
+	env.CreateImmutableBinding("y", true)
+	env.InitializeBinding("y", Number(2))
+	test.AssertEqual(t, errorType(env.SetMutableBinding("y", Number(3), false)), TypeError)
+
+	// The name of a sloppy named function expression ignores assignments
+	env.CreateImmutableBinding("f", false)
+	env.InitializeBinding("f", Null())
+	test.AssertEqual(t, env.SetMutableBinding("f", Number(3), false), nil)
+	test.AssertEqual(t, errorType(env.SetMutableBinding("f", Number(3), true)), TypeError)
+	v, _ = env.GetBindingValue("f", false)
+	test.AssertEqual(t, v.IsNull(), true)
+
+	ok, _ := env.DeleteBinding("x")
+	test.AssertEqual(t, ok, false)
+	env.CreateMutableBinding("z", true)
+	ok, _ = env.DeleteBinding("z")
+	test.AssertEqual(t, ok, true)
+	ok, _ = env.HasBinding("z")
+	test.AssertEqual(t, ok, false)
+
+	// Unknown names are reported rather than dereferenced
+	test.AssertEqual(t, errorType(env.InitializeBinding("z", Null())), ReferenceError)
+	_, err = env.GetBindingValue("z", true)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+	_, err = env.DeleteBinding("z")
+	test.AssertEqual(t, errorType(err), ReferenceError)
+	_, err = NewModuleEnvironment(nil).GetBindingValue("z", true)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+}
+
+func TestResolveBinding(t *testing.T) {
+	g := NewObject(nil)
+	global := NewGlobalEnvironment(g, g)
+	global.CreateGlobalVarBinding("v", false)
+	global.CreateImmutableBinding("c", true)
+	global.InitializeBinding("c", Number(1))
+	inner := NewDeclarativeEnvironment(global)
+	inner.CreateMutableBinding("v", false)
+	inner.InitializeBinding("v", Str("shadow"))
+
+	ref, _ := ResolveBinding(inner, "v", true)
+	test.AssertEqual(t, ref.Environment(), Environment(inner))
+	v, _ := ref.GetValue()
+	test.AssertEqual(t, v.String(), "shadow")
+	ref, _ = ResolveBinding(inner, "c", false)
+	test.AssertEqual(t, errorType(ref.PutValue(Number(2))), TypeError)
+
+	// Unresolvable references
+	ref, _ = ResolveBinding(inner, "u", true)
+	test.AssertEqual(t, ref.IsUnresolvable(), true)
+	_, err := ref.GetValue()
+	test.AssertEqual(t, errorType(err), ReferenceError)
+	test.AssertEqual(t, errorType(ref.PutValue(Number(1))), ReferenceError)
+	ref, _ = ResolveBinding(inner, "u", false)
+	test.AssertEqual(t, ref.PutValue(Number(1)), nil)
+	v, _ = Get(g, StringKey("u"))
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+
+	// Implicit globals are deletable, var declarations are not
+	ok, _ := global.DeleteBinding("u")
+	test.AssertEqual(t, ok, true)
+	ok, _ = global.DeleteBinding("v")
+	test.AssertEqual(t, ok, false)
+	test.AssertEqual(t, global.HasVarDeclaration("v"), true)
+	test.AssertEqual(t, errorType(global.CreateMutableBinding("c", false)), TypeError)
+
+	this, _ := ResolveThisBinding(inner)
+	test.AssertEqual(t, this.AsObject(), g)
+}
+
+func TestObjectEnvironment(t *testing.T) {
+	o := NewObject(nil)
+	CreateDataProperty(o, StringKey("a"), Number(1))
+	CreateDataProperty(o, StringKey("b"), Number(2))
+	unscopables := NewObject(nil)
+	CreateDataProperty(unscopables, StringKey("b"), Bool(true))
+	CreateDataProperty(o, SymbolKey(SymbolUnscopables), unscopables.Value())
+
+	outer := NewDeclarativeEnvironment(nil)
+	outer.CreateMutableBinding("b", false)
+	outer.InitializeBinding("b", Str("outer"))
+	with := NewObjectEnvironment(o, true, outer)
+
+	ref, _ := ResolveBinding(with, "a", false)
+	v, _ := ref.GetValue()
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	test.AssertEqual(t, ref.ThisValue().AsObject(), o)
+	ref, _ = ResolveBinding(with, "b", false)
+	v, _ = ref.GetValue()
+	test.AssertEqual(t, v.String(), "outer")
+
+	// A binding deleted after resolution reads as undefined in sloppy mode
+	ref, _ = ResolveBinding(with, "a", false)
+	o.Delete(StringKey("a"))
+	v, err := ref.GetValue()
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.IsUndefined(), true)
+	ref.strict = true
+	_, err = ref.GetValue()
+	test.AssertEqual(t, errorType(err), ReferenceError)
+}
+
+func TestFunctionEnvironment(t *testing.T) {
+	g := NewObject(nil)
+	global := NewGlobalEnvironment(g, g)
+
+	// A derived constructor binds this when super() returns
+	ctor := NewFunctionEnvironment(nil, ThisUninitialized, Undefined(), global)
+	arrow := NewFunctionEnvironment(nil, ThisLexical, Undefined(), ctor)
+	_, err := ResolveThisBinding(arrow)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+	this := NewObject(nil)
+	test.AssertEqual(t, ctor.BindThisValue(this.Value()), nil)
+	v, _ := ResolveThisBinding(arrow)
+	test.AssertEqual(t, v.AsObject(), this)
+	test.AssertEqual(t, errorType(ctor.BindThisValue(this.Value())), ReferenceError)
+}
+
+func TestModuleEnvironment(t *testing.T) {
+	exporter := NewModuleEnvironment(nil)
+	exporter.CreateMutableBinding("count", false)
+	importer := NewModuleEnvironment(nil)
+	importer.CreateImportBinding("n", exporter, "count")
+
+	_, err := importer.GetBindingValue("n", true)
+	test.AssertEqual(t, errorType(err), ReferenceError)
+
+	// Imports are live views of the exported binding
+	exporter.InitializeBinding("count", Number(1))
+	exporter.SetMutableBinding("count", Number(2), true)
+	v, _ := importer.GetBindingValue("n", true)
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	test.AssertEqual(t, errorType(importer.SetMutableBinding("n", Number(3), true)), TypeError)
+
+	this, _ := ResolveThisBinding(importer)
+	test.AssertEqual(t, this.IsUndefined(), true)
+}
+
+// closure is a function value capturing its environment, as function
+// objects do, that returns the value of i.
+type closure struct {
+	env Environment
+}
+
+func (c closure) call(t *testing.T) float64 {
+	ref, _ := ResolveBinding(c.env, "i", true)
+	v, err := ref.GetValue()
+	test.AssertEqual(t, err, nil)
+	return v.AsNumber()
+}
+
+// runLoop evaluates for (<decl> i = 0; i < 3; i++) fns.push(() => i)
+// the way the evaluator does, with per-iteration copies for let.
+func runLoop(t *testing.T, let bool) []closure {
+	g := NewObject(nil)
+	global := NewGlobalEnvironment(g, g)
+	var fns []closure
+
+	env := Environment(global)
+	var names []string
+	if let {
+		loop := NewDeclarativeEnvironment(global)
+		loop.CreateMutableBinding("i", false)
+		loop.InitializeBinding("i", Number(0))
+		env = loop
+		names = []string{"i"}
+	} else {
+		global.CreateGlobalVarBinding("i", false)
+		global.SetMutableBinding("i", Number(0), true)
+	}
+
+	copyEnv := func() {
+		if let {
+			iteration, err := CreatePerIterationEnvironment(env.(*DeclarativeEnvironment), names)
+			test.AssertEqual(t, err, nil)
+			env = iteration
+		}
+	}
+
+	copyEnv()
+	for {
+		ref, _ := ResolveBinding(env, "i", true)
+		i, _ := ref.GetValue()
+		if i.AsNumber() >= 3 {
+			break
+		}
+		fns = append(fns, closure{env})
+		copyEnv()
+		ref, _ = ResolveBinding(env, "i", true)
+		i, _ = ref.GetValue()
+		ref.PutValue(Number(i.AsNumber() + 1))
+	}
+	return fns
+}
+
+func TestClosureInLoop(t *testing.T) {
+	fns := runLoop(t, true)
+	test.AssertEqual(t, len(fns), 3)
+	for i, f := range fns {
+		test.AssertEqual(t, f.call(t), float64(i))
+	}
+
+	for _, f := range runLoop(t, false) {
+		test.AssertEqual(t, f.call(t), 3.0)
+	}
+}
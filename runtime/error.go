@@ -0,0 +1,169 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorType identifies a native error raised by the engine.
+type ErrorType uint8
+
+// Definition of native error types
+const (
+	_ ErrorType = iota // a value thrown by script
+	TypeError
+	RangeError
+	ReferenceError
+	SyntaxError
+)
+
+var errorTypeNames = [...]string{
+	TypeError:      "TypeError",
+	RangeError:     "RangeError",
+	ReferenceError: "ReferenceError",
+	SyntaxError:    "SyntaxError",
+}
+
+func (t ErrorType) String() string {
+	return errorTypeNames[t]
+}
+
+// Exception is a throw completion returned as a Go error. Errors raised
+// by the engine carry their native error type and message; values
+// thrown by script are held in Value.
+type Exception struct {
+	Type    ErrorType
+	Message string
+	Value   Value
+}
+
+// Throw returns the throw completion for v.
+func Throw(v Value) *Exception {
+	return &Exception{Value: v}
+}
+
+func (e *Exception) Error() string {
+	if e.Type != 0 {
+		return e.Type.String() + ": " + e.Message
+	}
+	return "Uncaught " + e.Value.String()
+}
+
+// throwError creates an engine error of type typ.
+func throwError(typ ErrorType, format string, a ...interface{}) *Exception {
+	return &Exception{Type: typ, Message: fmt.Sprintf(format, a...)}
+}
+
+// throwTypeError creates an engine TypeError.
+func throwTypeError(format string, a ...interface{}) *Exception {
+	return throwError(TypeError, format, a...)
+}
+
+// initError creates the Error constructor, the native error
+// constructors such as TypeError, and their prototypes.
+func (r *Realm) initError() {
+	r.ErrorPrototype = NewObject(r.ObjectPrototype)
+	errorCtor := r.newErrorConstructor("Error", 0, r.FunctionPrototype)
+	for typ := TypeError; typ <= SyntaxError; typ++ {
+		r.nativeErrorPrototypes[typ] = NewObject(r.ErrorPrototype)
+		r.newErrorConstructor(typ.String(), typ, errorCtor)
+	}
+
+	r.defineMethod(r.ErrorPrototype, "toString", 0, func(this Value, args []Value) (Value, error) {
+		o := this.AsObject()
+		if o == nil {
+			return Undefined(), throwTypeError("Error.prototype.toString called on non-object")
+		}
+		var parts [2]*String
+		for i, p := range [2]struct{ key, def string }{{"name", "Error"}, {"message", ""}} {
+			v, err := Get(o, StringKey(p.key))
+			if err != nil {
+				return Undefined(), err
+			}
+			if v.IsUndefined() {
+				parts[i] = NewString(p.def)
+			} else if parts[i], err = ToString(v); err != nil {
+				return Undefined(), err
+			}
+		}
+		switch {
+		case parts[0].Len() == 0:
+			return parts[1].Value(), nil
+		case parts[1].Len() == 0:
+			return parts[0].Value(), nil
+		}
+		return parts[0].Concat(NewString(": ")).Concat(parts[1]).Value(), nil
+	})
+}
+
+// newErrorConstructor creates the global error constructor name, whose
+// instances are errors of type typ and whose own prototype is parent.
+func (r *Realm) newErrorConstructor(name string, typ ErrorType, parent *Object) *Object {
+	var ctor *Object
+	ctor = r.NewBuiltinConstructor(name, 1, func(args []Value, newTarget *Object) (Value, error) {
+		if newTarget == nil {
+			newTarget = ctor
+		}
+		o, err := r.OrdinaryCreateFromConstructor(newTarget, func(r *Realm) *Object {
+			return r.errorPrototype(typ)
+		})
+		if err != nil {
+			return Undefined(), err
+		}
+		if msg := argument(args, 0); !msg.IsUndefined() {
+			s, err := ToString(msg)
+			if err != nil {
+				return Undefined(), err
+			}
+			o.DefineOwnProperty(StringKey("message"), DataDescriptor(s.Value(), true, false, true))
+		}
+		if options := argument(args, 1).AsObject(); options != nil && options.HasProperty(StringKey("cause")) {
+			cause, err := Get(options, StringKey("cause"))
+			if err != nil {
+				return Undefined(), err
+			}
+			o.DefineOwnProperty(StringKey("cause"), DataDescriptor(cause, true, false, true))
+		}
+		return o.Value(), nil
+	})
+	ctor.SetPrototypeOf(parent)
+	proto := r.errorPrototype(typ)
+	ctor.DefineOwnProperty(StringKey("prototype"), DataDescriptor(proto.Value(), false, false, false))
+	proto.DefineOwnProperty(StringKey("constructor"), DataDescriptor(ctor.Value(), true, false, true))
+	proto.DefineOwnProperty(StringKey("name"), DataDescriptor(Str(name), true, false, true))
+	proto.DefineOwnProperty(StringKey("message"), DataDescriptor(Str(""), true, false, true))
+	r.GlobalObject.DefineOwnProperty(StringKey(name), DataDescriptor(ctor.Value(), true, false, true))
+	return ctor
+}
+
+// NewError creates an error object of type typ, or a plain Error if
+// typ is 0.
+func (r *Realm) NewError(typ ErrorType, message string) *Object {
+	o := NewObject(r.errorPrototype(typ))
+	o.DefineOwnProperty(StringKey("message"), DataDescriptor(Str(message), true, false, true))
+	return o
+}
+
+// errorPrototype returns the prototype of errors of type typ, or of
+// plain Errors if typ is 0.
+func (r *Realm) errorPrototype(typ ErrorType) *Object {
+	if typ == 0 {
+		return r.ErrorPrototype
+	}
+	return r.nativeErrorPrototypes[typ]
+}
+
+// ThrownValue returns the value thrown by the throw completion err:
+// the value thrown by script, or an error object for engine errors.
+// Other errors, such as those returned by host functions, become Error
+// objects.
+func (r *Realm) ThrownValue(err error) Value {
+	var e *Exception
+	if !errors.As(err, &e) {
+		return r.NewError(0, err.Error()).Value()
+	}
+	if e.Type == 0 {
+		return e.Value
+	}
+	return r.NewError(e.Type, e.Message).Value()
+}
@@ -0,0 +1,391 @@
+package runtime
+
+import "math"
+
+// ThisMode determines how the this value of a call to an ECMAScript
+// function is bound.
+type ThisMode uint8
+
+// Definition of this modes
+const (
+	ThisModeGlobal  ThisMode = iota // sloppy functions: nullish this is the global this
+	ThisModeStrict                  // strict functions: this is passed unchanged
+	ThisModeLexical                 // arrow functions: this is that of the outer environment
+)
+
+// ConstructorKind tells whether an ECMAScript constructor allocates its
+// this value or receives it from a super() call.
+type ConstructorKind uint8
+
+// Definition of constructor kinds
+const (
+	BaseConstructor ConstructorKind = iota
+	DerivedConstructor
+)
+
+// FunctionBody evaluates the body of an ECMAScript function in env,
+// the environment of the call, after instantiating the parameters with
+// args. It returns the value of a return statement, or undefined.
+type FunctionBody func(f *Object, env *FunctionEnvironment, args []Value) (Value, error)
+
+// function holds the internal slots of an ECMAScript function object.
+type function struct {
+	realm      *Realm
+	env        Environment
+	body       FunctionBody
+	thisMode   ThisMode
+	kind       ConstructorKind
+	homeObject *Object // for methods using super
+
+	classConstructor bool
+	fields           []classField
+	privateMethods   []*PrivateElement
+}
+
+// NewFunction creates an ECMAScript function object closing over env,
+// as OrdinaryFunctionCreate does. The function is not a constructor
+// until MakeConstructor is applied to it.
+func (r *Realm) NewFunction(body FunctionBody, env Environment, thisMode ThisMode) *Object {
+	f := NewObject(r.FunctionPrototype)
+	f.internal = &function{realm: r, env: env, body: body, thisMode: thisMode}
+	f.call = func(this Value, args []Value) (Value, error) {
+		return f.function().callFunction(f, this, args)
+	}
+	return f
+}
+
+// MakeConstructor gives the function f a [[Construct]] method and a
+// prototype property. If prototype is nil, a new object inheriting from
+// %Object.prototype% of the realm of f is created with a constructor
+// property set to f.
+func MakeConstructor(f *Object, writablePrototype bool, prototype *Object) {
+	f.construct = func(args []Value, newTarget *Object) (Value, error) {
+		return f.function().constructFunction(f, args, newTarget)
+	}
+	if prototype == nil {
+		prototype = NewObject(f.function().realm.ObjectPrototype)
+		prototype.DefineOwnProperty(StringKey("constructor"), DataDescriptor(f.Value(), writablePrototype, false, true))
+	}
+	f.DefineOwnProperty(StringKey("prototype"), DataDescriptor(prototype.Value(), writablePrototype, false, false))
+}
+
+// MakeMethod sets the object whose prototype super refers to in f.
+func MakeMethod(f *Object, homeObject *Object) {
+	f.function().homeObject = homeObject
+}
+
+// SetFunctionName defines the name property of f. Symbol names become
+// their description in brackets; prefix is "get", "set" or "bound".
+func SetFunctionName(f *Object, name PropertyKey, prefix string) {
+	var s *String
+	if name.IsSymbol() {
+		s = NewString("")
+		if d := name.Symbol().Description(); !d.IsUndefined() {
+			s = NewString("[").Concat(d.AsString()).Concat(NewString("]"))
+		}
+	} else {
+		s = name.Value().AsString()
+	}
+	if prefix != "" {
+		s = NewString(prefix + " ").Concat(s)
+	}
+	f.DefineOwnProperty(StringKey("name"), DataDescriptor(s.Value(), false, false, true))
+}
+
+// SetFunctionLength defines the length property of f, the number of
+// parameters before the first one with a default or rest.
+func SetFunctionLength(f *Object, length float64) {
+	f.DefineOwnProperty(StringKey("length"), DataDescriptor(Number(length), false, false, true))
+}
+
+// callFunction implements [[Call]] for ECMAScript functions.
+func (fn *function) callFunction(f *Object, this Value, args []Value) (Value, error) {
+	if fn.classConstructor {
+		return Undefined(), throwTypeError("Class constructor cannot be invoked without 'new'")
+	}
+	env := fn.newEnvironment(f, Undefined())
+	if err := fn.bindThis(env, this); err != nil {
+		return Undefined(), err
+	}
+	return fn.body(f, env, args)
+}
+
+// constructFunction implements [[Construct]] for ECMAScript functions.
+func (fn *function) constructFunction(f *Object, args []Value, newTarget *Object) (Value, error) {
+	var this *Object
+	if fn.kind == BaseConstructor {
+		proto, err := fn.realm.GetPrototypeFromConstructor(newTarget, func(r *Realm) *Object {
+			return r.ObjectPrototype
+		})
+		if err != nil {
+			return Undefined(), err
+		}
+		this = NewObject(proto)
+	}
+
+	env := fn.newEnvironment(f, newTarget.Value())
+	if this != nil {
+		env.BindThisValue(this.Value())
+		if err := InitializeInstanceElements(this, f); err != nil {
+			return Undefined(), err
+		}
+	}
+	result, err := fn.body(f, env, args)
+	if err != nil || result.IsObject() {
+		return result, err
+	}
+	if this != nil {
+		return this.Value(), nil
+	}
+	if !result.IsUndefined() {
+		return Undefined(), throwTypeError("Derived constructors may only return object or undefined")
+	}
+	return env.GetThisBinding()
+}
+
+// newEnvironment creates the environment of a call to f.
+func (fn *function) newEnvironment(f *Object, newTarget Value) *FunctionEnvironment {
+	status := ThisUninitialized
+	if fn.thisMode == ThisModeLexical {
+		status = ThisLexical
+	}
+	return NewFunctionEnvironment(f, status, newTarget, fn.env)
+}
+
+// bindThis binds the this value of a call in env, as OrdinaryCallBindThis.
+func (fn *function) bindThis(env *FunctionEnvironment, this Value) error {
+	switch fn.thisMode {
+	case ThisModeLexical:
+		return nil
+	case ThisModeGlobal:
+		if this.IsNullish() {
+			this = fn.realm.GlobalEnv.thisValue.Value()
+		} else {
+			o, err := fn.realm.ToObject(this)
+			if err != nil {
+				return err
+			}
+			this = o.Value()
+		}
+	}
+	return env.BindThisValue(this)
+}
+
+// GetPrototypeFromConstructor returns the prototype property of the
+// constructor c or, if it is not an object, the intrinsic prototype of
+// the realm of c that objects created by c default to. intrinsic
+// selects it from a realm; r is used if c has no realm.
+func (r *Realm) GetPrototypeFromConstructor(c *Object, intrinsic func(r *Realm) *Object) (*Object, error) {
+	proto, err := Get(c, StringKey("prototype"))
+	if err != nil {
+		return nil, err
+	}
+	if p := proto.AsObject(); p != nil {
+		return p, nil
+	}
+	if realm := GetFunctionRealm(c); realm != nil {
+		return intrinsic(realm), nil
+	}
+	return intrinsic(r), nil
+}
+
+// OrdinaryCreateFromConstructor creates an ordinary object whose
+// prototype is taken from the constructor c, as done for new c().
+func (r *Realm) OrdinaryCreateFromConstructor(c *Object, intrinsic func(r *Realm) *Object) (*Object, error) {
+	proto, err := r.GetPrototypeFromConstructor(c, intrinsic)
+	if err != nil {
+		return nil, err
+	}
+	return NewObject(proto), nil
+}
+
+// GetFunctionRealm returns the realm the function f was created in, or
+// nil for functions not created by a realm, such as native functions.
+func GetFunctionRealm(f *Object) *Realm {
+	switch s := f.internal.(type) {
+	case *function:
+		return s.realm
+	case *boundFunction:
+		return GetFunctionRealm(s.target)
+	case *Realm:
+		return s
+	}
+	return nil
+}
+
+// boundFunction holds the internal slots of a bound function exotic object.
+type boundFunction struct {
+	target *Object
+	this   Value
+	args   []Value
+}
+
+// BoundFunctionCreate creates a function that calls target with the
+// given this value and leading arguments.
+func BoundFunctionCreate(target *Object, this Value, args []Value) *Object {
+	f := NewObject(target.GetPrototypeOf())
+	f.internal = &boundFunction{target: target, this: this, args: args}
+	n := len(args)
+	f.call = func(_ Value, rest []Value) (Value, error) {
+		return Call(target.Value(), this, append(args[:n:n], rest...)...)
+	}
+	if target.construct != nil {
+		f.construct = func(rest []Value, newTarget *Object) (Value, error) {
+			if newTarget == f {
+				newTarget = target
+			}
+			return Construct(target, append(args[:n:n], rest...), newTarget)
+		}
+	}
+	return f
+}
+
+// CreateListFromArrayLike returns the elements of the array-like
+// object v, as used by Function.prototype.apply.
+func CreateListFromArrayLike(v Value) ([]Value, error) {
+	o := v.AsObject()
+	if o == nil {
+		return nil, throwTypeError("CreateListFromArrayLike called on non-object")
+	}
+	length, err := Get(o, StringKey("length"))
+	if err != nil {
+		return nil, err
+	}
+	n, err := ToLength(length)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxArguments {
+		return nil, throwError(RangeError, "Too many arguments in function call")
+	}
+
+	list := make([]Value, n)
+	for i := range list {
+		list[i], err = Get(o, StringKey(numberToString(float64(i))))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// maxArguments is the largest argument list created from an array-like.
+const maxArguments = 1 << 24
+
+// initFunctionPrototype defines the methods of %Function.prototype%.
+func (r *Realm) initFunctionPrototype() {
+	fp := r.FunctionPrototype
+	r.defineMethod(fp, "call", 1, func(this Value, args []Value) (Value, error) {
+		if !IsCallable(this) {
+			return Undefined(), throwTypeError("Function.prototype.call called on non-function")
+		}
+		thisArg := argument(args, 0)
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		return Call(this, thisArg, args...)
+	})
+
+	r.defineMethod(fp, "apply", 2, func(this Value, args []Value) (Value, error) {
+		if !IsCallable(this) {
+			return Undefined(), throwTypeError("Function.prototype.apply called on non-function")
+		}
+		argArray := argument(args, 1)
+		if argArray.IsNullish() {
+			return Call(this, argument(args, 0))
+		}
+		list, err := CreateListFromArrayLike(argArray)
+		if err != nil {
+			return Undefined(), err
+		}
+		return Call(this, argument(args, 0), list...)
+	})
+
+	r.defineMethod(fp, "bind", 1, func(this Value, args []Value) (Value, error) {
+		if !IsCallable(this) {
+			return Undefined(), throwTypeError("Bind must be called on a function")
+		}
+		target := this.AsObject()
+		var boundArgs []Value
+		if len(args) > 1 {
+			boundArgs = append(boundArgs, args[1:]...)
+		}
+		f := BoundFunctionCreate(target, argument(args, 0), boundArgs)
+
+		length := 0.0
+		if HasOwnProperty(target, StringKey("length")) {
+			targetLen, err := Get(target, StringKey("length"))
+			if err != nil {
+				return Undefined(), err
+			}
+			if targetLen.kind == KindNumber {
+				l, _ := ToIntegerOrInfinity(targetLen)
+				length = math.Max(0, l-float64(len(boundArgs)))
+			}
+		}
+		SetFunctionLength(f, length)
+
+		name, err := Get(target, StringKey("name"))
+		if err != nil {
+			return Undefined(), err
+		}
+		if name.kind != KindString {
+			name = Str("")
+		}
+		SetFunctionName(f, KeyOf(name.AsString()), "bound")
+		return f.Value(), nil
+	})
+}
+
+// argument returns args[i], or undefined if fewer arguments were passed.
+func argument(args []Value, i int) Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return Undefined()
+}
+
+// GetNewTarget returns the value of new.target in env.
+func GetNewTarget(env Environment) Value {
+	return GetThisEnvironment(env).(*FunctionEnvironment).NewTarget()
+}
+
+// superBase returns the object super property lookups in env start
+// from, and the this value they use as receiver.
+func superBase(env Environment) (*Object, Value, error) {
+	thisEnv := GetThisEnvironment(env).(*FunctionEnvironment)
+	this, err := thisEnv.GetThisBinding()
+	if err != nil {
+		return nil, Undefined(), err
+	}
+	base := thisEnv.GetSuperBase()
+	if base == nil {
+		return nil, Undefined(), throwTypeError("Cannot read properties of null")
+	}
+	return base, this, nil
+}
+
+// SuperGet evaluates super[key] in env.
+func SuperGet(env Environment, key PropertyKey) (Value, error) {
+	base, this, err := superBase(env)
+	if err != nil {
+		return Undefined(), err
+	}
+	return base.Get(key, this)
+}
+
+// SuperSet evaluates super[key] = v in env.
+func SuperSet(env Environment, key PropertyKey, v Value, strict bool) error {
+	base, this, err := superBase(env)
+	if err != nil {
+		return err
+	}
+	ok, err := base.Set(key, v, this)
+	if err != nil {
+		return err
+	}
+	if !ok && strict {
+		return throwTypeError("Cannot assign to read only property '%s'", key)
+	}
+	return nil
+}
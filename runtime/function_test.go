@@ -0,0 +1,235 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// thisBody returns the this value of the call.
+func thisBody(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+	return ResolveThisBinding(env)
+}
+
+func TestFunctionThis(t *testing.T) {
+	r := NewRealm()
+	sloppy := r.NewFunction(thisBody, r.GlobalEnv, ThisModeGlobal).Value()
+	strict := r.NewFunction(thisBody, r.GlobalEnv, ThisModeStrict).Value()
+
+	v, _ := Call(sloppy, Undefined())
+	test.AssertEqual(t, v.AsObject(), r.GlobalObject)
+	v, _ = Call(strict, Undefined())
+	test.AssertEqual(t, v.IsUndefined(), true)
+	v, _ = Call(sloppy, Str("ab"))
+	test.AssertEqual(t, v.AsObject().GetPrototypeOf(), r.StringPrototype)
+	length, _ := Get(v.AsObject(), StringKey("length"))
+	test.AssertEqual(t, length.AsNumber(), 2.0)
+	v, _ = Call(strict, Str("ab"))
+	test.AssertEqual(t, v.String(), "ab")
+
+	// Arrow functions see the this and new.target of their outer function
+	var arrow *Object
+	outer := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		arrow = r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+			test.AssertEqual(t, GetNewTarget(env).IsUndefined(), true)
+			return ResolveThisBinding(env)
+		}, env, ThisModeLexical)
+		return Undefined(), nil
+	}, r.GlobalEnv, ThisModeStrict)
+	this := NewObject(nil)
+	Call(outer.Value(), this.Value())
+	v, _ = Call(arrow.Value(), Number(1))
+	test.AssertEqual(t, v.AsObject(), this)
+	_, err := Construct(arrow, nil, nil)
+	test.AssertEqual(t, errorType(err), TypeError)
+}
+
+func TestFunctionConstruct(t *testing.T) {
+	r := NewRealm()
+	var result Value
+	f := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		test.AssertEqual(t, GetNewTarget(env).AsObject(), f)
+		this, _ := env.GetThisBinding()
+		CreateDataProperty(this.AsObject(), StringKey("x"), argument(args, 0))
+		return result, nil
+	}, r.GlobalEnv, ThisModeStrict)
+	MakeConstructor(f, true, nil)
+	SetFunctionName(f, StringKey("F"), "")
+	SetFunctionLength(f, 1)
+
+	o, err := Construct(f, []Value{Number(1)}, nil)
+	test.AssertEqual(t, err, nil)
+	x, _ := Get(o.AsObject(), StringKey("x"))
+	test.AssertEqual(t, x.AsNumber(), 1.0)
+	proto, _ := Get(f, StringKey("prototype"))
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), proto.AsObject())
+	ctor, _ := Get(proto.AsObject(), StringKey("constructor"))
+	test.AssertEqual(t, ctor.AsObject(), f)
+
+	// Returning a primitive from a base constructor is ignored
+	result = Number(5)
+	o, _ = Construct(f, nil, nil)
+	test.AssertEqual(t, o.IsObject(), true)
+	result = NewObject(nil).Value()
+	o, _ = Construct(f, nil, nil)
+	test.AssertEqual(t, o.AsObject(), result.AsObject())
+
+	// A non-object prototype falls back to %Object.prototype%
+	result = Undefined()
+	f.DefineOwnProperty(StringKey("prototype"), PropertyDescriptor{Value: Null(), Fields: HasValue})
+	o, _ = Construct(f, nil, nil)
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), r.ObjectPrototype)
+
+	// The fallback comes from the realm of new.target, which bound
+	// functions take from their target
+	other := NewRealm()
+	newTarget := other.NewFunction(thisBody, other.GlobalEnv, ThisModeStrict)
+	MakeConstructor(newTarget, true, nil)
+	newTarget.DefineOwnProperty(StringKey("prototype"), PropertyDescriptor{Value: Null(), Fields: HasValue})
+	f = r.NewFunction(thisBody, r.GlobalEnv, ThisModeStrict)
+	MakeConstructor(f, true, nil)
+	o, _ = Construct(f, nil, newTarget)
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), other.ObjectPrototype)
+	bound, _ := other.invoke(newTarget.Value(), "bind")
+	o, _ = Construct(f, nil, bound.AsObject())
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), other.ObjectPrototype)
+	typeError, _ := Get(r.GlobalObject, StringKey("TypeError"))
+	o, _ = Construct(typeError.AsObject(), nil, newTarget)
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), other.nativeErrorPrototypes[TypeError])
+	promise, _ := Get(r.GlobalObject, StringKey("Promise"))
+	newTarget.DefineOwnProperty(StringKey("prototype"), PropertyDescriptor{Value: Number(1), Fields: HasValue})
+	executor := r.NewBuiltinFunction("", 2, func(this Value, args []Value) (Value, error) {
+		return Undefined(), nil
+	})
+	o, _ = Construct(promise.AsObject(), []Value{executor.Value()}, newTarget)
+	test.AssertEqual(t, o.AsObject().GetPrototypeOf(), other.PromisePrototype)
+}
+
+func TestFunctionPrototypeMethods(t *testing.T) {
+	r := NewRealm()
+	f := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		this, _ := env.GetThisBinding()
+		list := []Value{this}
+		CreateDataProperty(f, StringKey("last"), r.CreateUnmappedArgumentsObject(append(list, args...)).Value())
+		return Undefined(), nil
+	}, r.GlobalEnv, ThisModeStrict)
+	MakeConstructor(f, true, nil)
+	SetFunctionName(f, StringKey("f"), "")
+	SetFunctionLength(f, 3)
+
+	last := func() []string {
+		v, _ := Get(f, StringKey("last"))
+		list, _ := CreateListFromArrayLike(v)
+		var s []string
+		for _, v := range list {
+			s = append(s, v.String())
+		}
+		return s
+	}
+	method := func(name string) Value {
+		v, _ := Get(f, StringKey(name))
+		return v
+	}
+
+	Call(method("call"), f.Value(), Str("this"), Number(1), Number(2))
+	test.AssertEqual(t, len(last()), 3)
+	test.AssertEqual(t, last()[0], "this")
+
+	arrayLike := NewObject(nil)
+	CreateDataProperty(arrayLike, StringKey("length"), Number(2))
+	CreateDataProperty(arrayLike, StringKey("0"), Str("a"))
+	Call(method("apply"), f.Value(), Null(), arrayLike.Value())
+	test.AssertEqual(t, len(last()), 3)
+	test.AssertEqual(t, last()[1], "a")
+	test.AssertEqual(t, last()[2], "undefined")
+	_, err := Call(method("apply"), f.Value(), Null(), Number(1))
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	bound, _ := Call(method("bind"), f.Value(), Str("bound this"), Number(1))
+	name, _ := Get(bound.AsObject(), StringKey("name"))
+	test.AssertEqual(t, name.String(), "bound f")
+
+	// Names keep lone surrogates
+	surrogate := NewStringFromUTF16([]uint16{0xD800})
+	g := r.NewFunction(thisBody, r.GlobalEnv, ThisModeStrict)
+	SetFunctionName(g, KeyOf(surrogate), "get")
+	name, _ = Get(g, StringKey("name"))
+	test.AssertEqual(t, name.AsString().Equals(NewString("get ").Concat(surrogate)), true)
+	g = r.NewFunction(thisBody, r.GlobalEnv, ThisModeStrict)
+	SetFunctionName(g, SymbolKey(NewSymbol(surrogate)), "")
+	name, _ = Get(g, StringKey("name"))
+	test.AssertEqual(t, name.AsString().Equals(NewString("[").Concat(surrogate).Concat(NewString("]"))), true)
+	length, _ := Get(bound.AsObject(), StringKey("length"))
+	test.AssertEqual(t, length.AsNumber(), 2.0)
+	Call(bound, Undefined(), Number(2))
+	test.AssertEqual(t, last()[0], "bound this")
+	test.AssertEqual(t, last()[2], "2")
+
+	// new ignores the bound this and uses the target as new.target
+	o, err := Construct(bound.AsObject(), nil, nil)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, last()[0] == "bound this", false)
+	ok, _ := InstanceofOperator(o, bound)
+	test.AssertEqual(t, ok, true)
+}
+
+func TestSuperProperty(t *testing.T) {
+	r := NewRealm()
+	base := NewObject(r.ObjectPrototype)
+	getter := r.NewBuiltinFunction("", 0, func(this Value, args []Value) (Value, error) {
+		return this, nil
+	})
+	base.DefineOwnProperty(StringKey("self"), AccessorDescriptor(getter.Value(), Undefined(), false, true))
+	home := NewObject(base)
+
+	m := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		test.AssertEqual(t, env.HasSuperBinding(), true)
+		return SuperGet(env, StringKey("self"))
+	}, r.GlobalEnv, ThisModeStrict)
+	MakeMethod(m, home)
+	CreateDataProperty(home, StringKey("m"), m.Value())
+
+	// super.self runs the getter of base with the original receiver
+	receiver := NewObject(home)
+	v, err := Call(m.Value(), receiver.Value())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.AsObject(), receiver)
+}
+
+func TestArgumentsObject(t *testing.T) {
+	r := NewRealm()
+	f := r.NewFunction(thisBody, r.GlobalEnv, ThisModeGlobal)
+	env := NewDeclarativeEnvironment(r.GlobalEnv)
+	for i, name := range []string{"a", "b"} {
+		env.CreateMutableBinding(name, false)
+		env.InitializeBinding(name, Number(float64(i)))
+	}
+
+	// function f(a, b) called as f(0): only a is mapped
+	args := r.CreateMappedArgumentsObject(f, []string{"a", "b"}, []Value{Number(0)}, env)
+	Set(args, StringKey("0"), Str("set"), true)
+	v, _ := env.GetBindingValue("a", false)
+	test.AssertEqual(t, v.String(), "set")
+	env.SetMutableBinding("a", Str("param"), false)
+	v, _ = Get(args, StringKey("0"))
+	test.AssertEqual(t, v.String(), "param")
+	desc, _ := args.GetOwnProperty(StringKey("0"))
+	test.AssertEqual(t, desc.Value.String(), "param")
+	Set(args, StringKey("1"), Str("unmapped"), true)
+	v, _ = env.GetBindingValue("b", false)
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	callee, _ := Get(args, StringKey("callee"))
+	test.AssertEqual(t, callee.AsObject(), f)
+
+	// Making the index read-only freezes its value and unmaps it
+	args.DefineOwnProperty(StringKey("0"), PropertyDescriptor{Writable: false, Fields: HasWritable})
+	env.SetMutableBinding("a", Str("later"), false)
+	v, _ = Get(args, StringKey("0"))
+	test.AssertEqual(t, v.String(), "param")
+
+	unmapped := r.CreateUnmappedArgumentsObject([]Value{Number(0)})
+	_, err := Get(unmapped, StringKey("callee"))
+	test.AssertEqual(t, errorType(err), TypeError)
+	length, _ := Get(unmapped, StringKey("length"))
+	test.AssertEqual(t, length.AsNumber(), 1.0)
+}
@@ -0,0 +1,217 @@
+package runtime
+
+// ResumeMode is how a suspended generator is resumed.
+type ResumeMode uint8
+
+// Definition of resume modes
+const (
+	ResumeNext ResumeMode = iota
+	ResumeThrow
+	ResumeReturn
+)
+
+// StepResult tells how a generator step ended.
+type StepResult uint8
+
+// Definition of step results
+const (
+	StepYield       StepResult = iota // the body yielded the value
+	StepYieldResult                   // yield* yielded an iterator result object of its inner iterator
+	StepReturn                        // the body completed, returning the value
+	StepAwait                         // an async body awaits the value
+)
+
+// GeneratorStep runs the body of a generator until its next yield or
+// its end. Generators are suspended without a goroutine: the step
+// function owns the state of the suspended body, as a resumable frame
+// of the evaluator, and picks up where the last yield left off.
+//
+// It is first called with ResumeNext when the generator starts, then
+// each time the generator is resumed, with the completion of the yield
+// expression it is suspended at: the value passed to next, the value
+// to throw, or the value to return, which may run finally blocks.
+type GeneratorStep func(mode ResumeMode, v Value) (Value, StepResult, error)
+
+// GeneratorStart instantiates the parameters of a call to a generator
+// function and returns the step function of its body.
+type GeneratorStart func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error)
+
+// generatorState is the [[GeneratorState]] of a generator object.
+type generatorState uint8
+
+// Definition of generator states
+const (
+	generatorSuspendedStart generatorState = iota
+	generatorSuspendedYield
+	generatorExecuting
+	generatorCompleted
+)
+
+// generator holds the internal slots of a generator object.
+type generator struct {
+	state generatorState
+	step  GeneratorStep
+}
+
+// NewGeneratorFunction creates a generator function: calling it runs
+// start and returns a generator object executing the body lazily.
+func (r *Realm) NewGeneratorFunction(start GeneratorStart, env Environment, thisMode ThisMode) *Object {
+	f := r.NewFunction(func(f *Object, env *FunctionEnvironment, args []Value) (Value, error) {
+		step, err := start(f, env, args)
+		if err != nil {
+			return Undefined(), err
+		}
+		g, err := r.OrdinaryCreateFromConstructor(f, func(r *Realm) *Object {
+			return r.GeneratorPrototype
+		})
+		if err != nil {
+			return Undefined(), err
+		}
+		g.internal = &generator{step: step}
+		return g.Value(), nil
+	}, env, thisMode)
+	f.SetPrototypeOf(r.GeneratorFunctionPrototype)
+	f.DefineOwnProperty(StringKey("prototype"), DataDescriptor(NewObject(r.GeneratorPrototype).Value(), true, false, false))
+	return f
+}
+
+// resume resumes the generator g, implementing next, throw and return.
+func (r *Realm) resume(g *generator, mode ResumeMode, v Value) (Value, error) {
+	if g.state == generatorExecuting {
+		return Undefined(), throwTypeError("Generator is already running")
+	}
+	if g.state == generatorSuspendedStart && mode != ResumeNext {
+		g.state = generatorCompleted
+	}
+	if g.state == generatorCompleted {
+		switch mode {
+		case ResumeThrow:
+			return Undefined(), Throw(v)
+		case ResumeReturn:
+			return r.CreateIterResultObject(v, true), nil
+		}
+		return r.CreateIterResultObject(Undefined(), true), nil
+	}
+
+	g.state = generatorExecuting
+	value, result, err := g.step(mode, v)
+	if err != nil || result == StepReturn {
+		g.state = generatorCompleted
+		g.step = nil
+		if err != nil {
+			return Undefined(), err
+		}
+		return r.CreateIterResultObject(value, true), nil
+	}
+	g.state = generatorSuspendedYield
+	if result == StepYieldResult {
+		return value, nil
+	}
+	return r.CreateIterResultObject(value, false), nil
+}
+
+// initGenerators creates %GeneratorFunction.prototype% and
+// %GeneratorPrototype%, the prototype of generator objects.
+func (r *Realm) initGenerators() {
+	r.GeneratorFunctionPrototype = NewObject(r.FunctionPrototype)
+	r.GeneratorPrototype = NewObject(r.IteratorPrototype)
+	r.GeneratorFunctionPrototype.DefineOwnProperty(StringKey("prototype"), DataDescriptor(r.GeneratorPrototype.Value(), false, false, true))
+	r.GeneratorPrototype.DefineOwnProperty(StringKey("constructor"), DataDescriptor(r.GeneratorFunctionPrototype.Value(), false, false, true))
+	r.GeneratorPrototype.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str("Generator"), false, false, true))
+
+	for _, m := range []struct {
+		name string
+		mode ResumeMode
+	}{{"next", ResumeNext}, {"return", ResumeReturn}, {"throw", ResumeThrow}} {
+		mode, name := m.mode, m.name
+		r.defineMethod(r.GeneratorPrototype, name, 1, func(this Value, args []Value) (Value, error) {
+			o := this.AsObject()
+			if o == nil || o.generator() == nil {
+				return Undefined(), throwTypeError("%s method called on incompatible receiver %s", name, this)
+			}
+			return r.resume(o.generator(), mode, argument(args, 0))
+		})
+	}
+}
+
+// DelegateResult tells how a resumption of a yield* expression ended.
+type DelegateResult uint8
+
+// Definition of delegation results
+const (
+	DelegateYield  DelegateResult = iota // yield the inner iterator result object with StepYieldResult
+	DelegateDone                         // the yield* expression evaluates to the value
+	DelegateReturn                       // the generator must return the value
+)
+
+// Delegation is the state of a yield* expression, which forwards the
+// resumptions of its generator to an inner iterator.
+type Delegation struct {
+	rec *IteratorRecord
+}
+
+// NewDelegation starts yield* over the iterable v. The generator must
+// then call Resume with ResumeNext and undefined.
+func (r *Realm) NewDelegation(v Value) (*Delegation, error) {
+	rec, err := r.GetIterator(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Delegation{rec: rec}, nil
+}
+
+// Resume forwards the completion the generator was resumed with to the
+// inner iterator.
+func (d *Delegation) Resume(mode ResumeMode, v Value) (Value, DelegateResult, error) {
+	iterator := d.rec.Iterator
+	var result, method Value
+	var err error
+	switch mode {
+	case ResumeNext:
+		result, err = Call(d.rec.NextMethod, iterator.Value(), v)
+
+	case ResumeThrow:
+		method, err = getMethod(iterator, StringKey("throw"))
+		if err != nil {
+			return Undefined(), DelegateDone, err
+		}
+		if method.IsUndefined() {
+			// The protocol is violated: close the iterator and report it
+			if err := IteratorClose(d.rec, nil); err != nil {
+				return Undefined(), DelegateDone, err
+			}
+			return Undefined(), DelegateDone, throwTypeError("The iterator does not provide a 'throw' method")
+		}
+		result, err = Call(method, iterator.Value(), v)
+
+	case ResumeReturn:
+		method, err = getMethod(iterator, StringKey("return"))
+		if err != nil {
+			return Undefined(), DelegateDone, err
+		}
+		if method.IsUndefined() {
+			return v, DelegateReturn, nil
+		}
+		result, err = Call(method, iterator.Value(), v)
+	}
+	if err != nil {
+		return Undefined(), DelegateDone, err
+	}
+
+	o := result.AsObject()
+	if o == nil {
+		return Undefined(), DelegateDone, throwTypeError("Iterator result %s is not an object", result)
+	}
+	done, err := IteratorComplete(o)
+	if err != nil {
+		return Undefined(), DelegateDone, err
+	}
+	if !done {
+		return result, DelegateYield, nil
+	}
+	value, err := IteratorValue(o)
+	if mode == ResumeReturn {
+		return value, DelegateReturn, err
+	}
+	return value, DelegateDone, err
+}
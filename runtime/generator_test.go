@@ -0,0 +1,177 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// newTestGenerator defines, as a hand-compiled state machine:
+//
+//	function* g(a) {
+//		try {
+//			const x = yield a;
+//			yield x * 2;
+//		} finally {
+//			cleanups++;
+//		}
+//		return "end";
+//	}
+func newTestGenerator(r *Realm, cleanups *int) *Object {
+	return r.NewGeneratorFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		a := argument(args, 0)
+		pc := 0
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			if pc > 0 && mode != ResumeNext {
+				*cleanups++
+				if mode == ResumeThrow {
+					return Undefined(), StepReturn, Throw(v)
+				}
+				return v, StepReturn, nil
+			}
+			pc++
+			switch pc {
+			case 1:
+				return a, StepYield, nil
+			case 2:
+				return Number(v.AsNumber() * 2), StepYield, nil
+			}
+			*cleanups++
+			return Str("end"), StepReturn, nil
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+}
+
+// resumeGenerator calls the method of g and returns the result.
+func resumeGenerator(t *testing.T, g Value, method string, v Value) (Value, bool, error) {
+	m, _ := Get(g.AsObject(), StringKey(method))
+	result, err := Call(m, g, v)
+	if err != nil {
+		return Undefined(), false, err
+	}
+	done, _ := IteratorComplete(result.AsObject())
+	value, _ := IteratorValue(result.AsObject())
+	return value, done, nil
+}
+
+func TestGenerator(t *testing.T) {
+	r := NewRealm()
+	cleanups := 0
+	g := newTestGenerator(r, &cleanups)
+
+	it, err := Call(g.Value(), Undefined(), Number(3))
+	test.AssertEqual(t, err, nil)
+	proto, _ := Get(g, StringKey("prototype"))
+	test.AssertEqual(t, it.AsObject().GetPrototypeOf(), proto.AsObject())
+	test.AssertEqual(t, proto.AsObject().GetPrototypeOf(), r.GeneratorPrototype)
+	_, err = Construct(g, nil, nil)
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	v, done, _ := resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, v.AsNumber(), 3.0)
+	test.AssertEqual(t, done, false)
+	v, done, _ = resumeGenerator(t, it, "next", Number(5))
+	test.AssertEqual(t, v.AsNumber(), 10.0)
+	test.AssertEqual(t, done, false)
+	v, done, _ = resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, v.String(), "end")
+	test.AssertEqual(t, done, true)
+	test.AssertEqual(t, cleanups, 1)
+	v, done, _ = resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, v.IsUndefined(), true)
+	test.AssertEqual(t, done, true)
+
+	// return and throw at a yield run the finally block
+	it, _ = Call(g.Value(), Undefined(), Number(3))
+	resumeGenerator(t, it, "next", Undefined())
+	v, done, _ = resumeGenerator(t, it, "return", Number(7))
+	test.AssertEqual(t, v.AsNumber(), 7.0)
+	test.AssertEqual(t, done, true)
+	test.AssertEqual(t, cleanups, 2)
+
+	it, _ = Call(g.Value(), Undefined(), Number(3))
+	resumeGenerator(t, it, "next", Undefined())
+	_, _, err = resumeGenerator(t, it, "throw", Str("boom"))
+	test.AssertEqual(t, err.(*Exception).Value.String(), "boom")
+	test.AssertEqual(t, cleanups, 3)
+
+	// Before the first next, the body never runs
+	it, _ = Call(g.Value(), Undefined(), Number(3))
+	_, _, err = resumeGenerator(t, it, "throw", Str("early"))
+	test.AssertEqual(t, err.(*Exception).Value.String(), "early")
+	_, done, _ = resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, done, true)
+	test.AssertEqual(t, cleanups, 3)
+
+	// A generator cannot resume itself
+	var self Value
+	reentrant := r.NewGeneratorFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			_, _, err := resumeGenerator(t, self, "next", Undefined())
+			return Undefined(), StepReturn, err
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+	self, _ = Call(reentrant.Value(), Undefined())
+	_, _, err = resumeGenerator(t, self, "next", Undefined())
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// Methods called on other objects report their own name
+	_, _, err = resumeGenerator(t, NewObject(r.GeneratorPrototype).Value(), "return", Undefined())
+	test.AssertEqual(t, err.(*Exception).Message, "return method called on incompatible receiver [object Object]")
+}
+
+func TestYieldStar(t *testing.T) {
+	r := NewRealm()
+	cleanups := 0
+	inner := newTestGenerator(r, &cleanups)
+
+	// function* outer() { const r = yield* g(1); return "outer " + r; }
+	outer := r.NewGeneratorFunction(func(f *Object, env *FunctionEnvironment, args []Value) (GeneratorStep, error) {
+		var d *Delegation
+		return func(mode ResumeMode, v Value) (Value, StepResult, error) {
+			if d == nil {
+				it, err := Call(inner.Value(), Undefined(), Number(1))
+				if err != nil {
+					return Undefined(), StepReturn, err
+				}
+				if d, err = r.NewDelegation(it); err != nil {
+					return Undefined(), StepReturn, err
+				}
+				mode, v = ResumeNext, Undefined()
+			}
+			result, res, err := d.Resume(mode, v)
+			switch {
+			case err != nil:
+				return Undefined(), StepReturn, err
+			case res == DelegateYield:
+				return result, StepYieldResult, nil
+			case res == DelegateReturn:
+				return result, StepReturn, nil
+			}
+			return Str("outer " + result.String()), StepReturn, nil
+		}, nil
+	}, r.GlobalEnv, ThisModeStrict)
+
+	it, _ := Call(outer.Value(), Undefined())
+	v, _, _ := resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	v, _, _ = resumeGenerator(t, it, "next", Number(4))
+	test.AssertEqual(t, v.AsNumber(), 8.0)
+	v, done, _ := resumeGenerator(t, it, "next", Undefined())
+	test.AssertEqual(t, v.String(), "outer end")
+	test.AssertEqual(t, done, true)
+
+	// return is forwarded to the inner generator, which runs its finally
+	it, _ = Call(outer.Value(), Undefined())
+	resumeGenerator(t, it, "next", Undefined())
+	v, done, _ = resumeGenerator(t, it, "return", Number(9))
+	test.AssertEqual(t, v.AsNumber(), 9.0)
+	test.AssertEqual(t, done, true)
+	test.AssertEqual(t, cleanups, 2)
+
+	// Spreading a generator collects the yielded values
+	it, _ = Call(outer.Value(), Undefined())
+	list, err := r.IterableToList(it)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, len(list), 2)
+}
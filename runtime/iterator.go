@@ -0,0 +1,253 @@
+package runtime
+
+import "unicode/utf16"
+
+// IteratorRecord is an iterator together with its next method.
+type IteratorRecord struct {
+	Iterator   *Object
+	NextMethod Value
+	Done       bool
+}
+
+// GetIterator returns an iterator over the iterable v, calling its
+// @@iterator method. Primitives such as strings are iterated through
+// their wrapper prototypes.
+func (r *Realm) GetIterator(v Value) (*IteratorRecord, error) {
+	method, err := r.GetV(v, SymbolKey(SymbolIterator))
+	if err != nil {
+		return nil, err
+	}
+	if !IsCallable(method) {
+		return nil, throwTypeError("%s is not iterable", v)
+	}
+	return GetIteratorFromMethod(v, method)
+}
+
+// GetIteratorFromMethod calls the @@iterator method of v.
+func GetIteratorFromMethod(v, method Value) (*IteratorRecord, error) {
+	iterator, err := Call(method, v)
+	if err != nil {
+		return nil, err
+	}
+	o := iterator.AsObject()
+	if o == nil {
+		return nil, throwTypeError("Result of the Symbol.iterator method is not an object")
+	}
+	next, err := Get(o, StringKey("next"))
+	if err != nil {
+		return nil, err
+	}
+	return &IteratorRecord{Iterator: o, NextMethod: next}, nil
+}
+
+// IteratorNext calls the next method of the iterator with v, or with
+// no argument if v is nil, and returns the iterator result object.
+func IteratorNext(rec *IteratorRecord, v *Value) (*Object, error) {
+	var result Value
+	var err error
+	if v == nil {
+		result, err = Call(rec.NextMethod, rec.Iterator.Value())
+	} else {
+		result, err = Call(rec.NextMethod, rec.Iterator.Value(), *v)
+	}
+	if err != nil {
+		rec.Done = true
+		return nil, err
+	}
+	if !result.IsObject() {
+		rec.Done = true
+		return nil, throwTypeError("Iterator result %s is not an object", result)
+	}
+	return result.AsObject(), nil
+}
+
+// IteratorComplete returns the done property of an iterator result.
+func IteratorComplete(result *Object) (bool, error) {
+	done, err := Get(result, StringKey("done"))
+	return ToBoolean(done), err
+}
+
+// IteratorValue returns the value property of an iterator result.
+func IteratorValue(result *Object) (Value, error) {
+	return Get(result, StringKey("value"))
+}
+
+// IteratorStepValue advances the iterator and returns the next value.
+// It reports true once the iterator is exhausted. rec.Done is set when
+// the iterator completes or throws, so that it must not be closed.
+func IteratorStepValue(rec *IteratorRecord) (Value, bool, error) {
+	result, err := IteratorNext(rec, nil)
+	if err != nil {
+		return Undefined(), false, err
+	}
+	done, err := IteratorComplete(result)
+	if err != nil || done {
+		rec.Done = true
+		return Undefined(), done, err
+	}
+	v, err := IteratorValue(result)
+	if err != nil {
+		rec.Done = true
+	}
+	return v, false, err
+}
+
+// IteratorClose calls the return method of an iterator that is left
+// before it is exhausted, as by break in for-of or by destructuring.
+// completion is the error that ended the iteration, or nil; it takes
+// precedence over errors raised by the return method.
+func IteratorClose(rec *IteratorRecord, completion error) error {
+	returnMethod, err := getMethod(rec.Iterator, StringKey("return"))
+	if err == nil && !returnMethod.IsUndefined() {
+		var result Value
+		result, err = Call(returnMethod, rec.Iterator.Value())
+		if completion == nil && err == nil && !result.IsObject() {
+			err = throwTypeError("Iterator result %s is not an object", result)
+		}
+	}
+	if completion != nil {
+		return completion
+	}
+	return err
+}
+
+// IterableToList returns the values produced by iterating v, as done
+// by spread arguments.
+func (r *Realm) IterableToList(v Value) ([]Value, error) {
+	rec, err := r.GetIterator(v)
+	if err != nil {
+		return nil, err
+	}
+	var list []Value
+	for {
+		v, done, err := IteratorStepValue(rec)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return list, nil
+		}
+		list = append(list, v)
+	}
+}
+
+// CreateIterResultObject returns { value: v, done: done }.
+func (r *Realm) CreateIterResultObject(v Value, done bool) Value {
+	o := NewObject(r.ObjectPrototype)
+	CreateDataProperty(o, StringKey("value"), v)
+	CreateDataProperty(o, StringKey("done"), Bool(done))
+	return o.Value()
+}
+
+// builtinIterator is the state of an array or string iterator.
+type builtinIterator struct {
+	proto *Object // the prototype whose next method accepts the iterator
+	next  func() (Value, bool, error)
+}
+
+// newBuiltinIterator creates an iterator object whose values are
+// produced by next until it reports true.
+func newBuiltinIterator(proto *Object, next func() (Value, bool, error)) *Object {
+	o := NewObject(proto)
+	o.internal = &builtinIterator{proto: proto, next: next}
+	return o
+}
+
+// defineIteratorNext defines the next method of the prototype of
+// built-in iterators, and their @@toStringTag.
+func (r *Realm) defineIteratorNext(proto *Object, tag string) {
+	r.defineMethod(proto, "next", 0, func(this Value, args []Value) (Value, error) {
+		o := this.AsObject()
+		if o == nil || o.iterator() == nil || o.iterator().proto != proto {
+			return Undefined(), throwTypeError("next method called on incompatible receiver %s", this)
+		}
+		it := o.iterator()
+		if it.next == nil {
+			return r.CreateIterResultObject(Undefined(), true), nil
+		}
+		v, done, err := it.next()
+		if err != nil || done {
+			// Iterators are exhausted once they complete or throw
+			it.next = nil
+		}
+		if err != nil {
+			return Undefined(), err
+		}
+		return r.CreateIterResultObject(v, done), nil
+	})
+	proto.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str(tag), false, false, true))
+}
+
+// CreateArrayIterator returns an iterator over the values of the
+// array-like object o, reading its length at each step.
+func (r *Realm) CreateArrayIterator(o *Object) *Object {
+	index := int64(0)
+	return newBuiltinIterator(r.ArrayIteratorPrototype, func() (Value, bool, error) {
+		length, err := Get(o, StringKey("length"))
+		if err != nil {
+			return Undefined(), false, err
+		}
+		n, err := ToLength(length)
+		if err != nil || index >= n {
+			return Undefined(), err == nil, err
+		}
+		v, err := Get(o, StringKey(numberToString(float64(index))))
+		if err != nil {
+			return Undefined(), false, err
+		}
+		index++
+		return v, false, nil
+	})
+}
+
+// createStringIterator returns an iterator over the code points of s.
+func (r *Realm) createStringIterator(s *String) *Object {
+	i := 0
+	return newBuiltinIterator(r.StringIteratorPrototype, func() (Value, bool, error) {
+		if i >= s.Len() {
+			return Undefined(), true, nil
+		}
+		j := i + 1
+		if j < s.Len() && utf16.IsSurrogate(rune(s.At(i))) && s.At(i) < 0xDC00 && s.At(j) >= 0xDC00 && s.At(j) <= 0xDFFF {
+			j++
+		}
+		cp := s.Substring(i, j)
+		i = j
+		return cp.Value(), false, nil
+	})
+}
+
+// initIterators creates %IteratorPrototype%, the prototypes of array
+// and string iterators, %Array.prototype.values% and the @@iterator
+// method of String.prototype.
+func (r *Realm) initIterators() {
+	r.IteratorPrototype = NewObject(r.ObjectPrototype)
+	iterator := r.NewBuiltinFunction("[Symbol.iterator]", 0, func(this Value, args []Value) (Value, error) {
+		return this, nil
+	})
+	r.IteratorPrototype.DefineOwnProperty(SymbolKey(SymbolIterator), DataDescriptor(iterator.Value(), true, false, true))
+
+	r.ArrayIteratorPrototype = NewObject(r.IteratorPrototype)
+	r.defineIteratorNext(r.ArrayIteratorPrototype, "Array Iterator")
+	r.ArrayValues = r.NewBuiltinFunction("values", 0, func(this Value, args []Value) (Value, error) {
+		o, err := r.ToObject(this)
+		if err != nil {
+			return Undefined(), err
+		}
+		return r.CreateArrayIterator(o).Value(), nil
+	})
+
+	r.StringIteratorPrototype = NewObject(r.IteratorPrototype)
+	r.defineIteratorNext(r.StringIteratorPrototype, "String Iterator")
+	stringIterator := r.NewBuiltinFunction("[Symbol.iterator]", 0, func(this Value, args []Value) (Value, error) {
+		if this.IsNullish() {
+			return Undefined(), throwTypeError("String.prototype[Symbol.iterator] called on null or undefined")
+		}
+		s, err := ToString(this)
+		if err != nil {
+			return Undefined(), err
+		}
+		return r.createStringIterator(s).Value(), nil
+	})
+	r.StringPrototype.DefineOwnProperty(SymbolKey(SymbolIterator), DataDescriptor(stringIterator.Value(), true, false, true))
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// newCountingIterator returns an iterator over 0, 1, 2, ... up to n
+// whose return method increments *closed and returns result.
+func newCountingIterator(r *Realm, n int, closed *int, result Value) *Object {
+	it := NewObject(r.IteratorPrototype)
+	i := 0
+	r.defineMethod(it, "next", 0, func(this Value, args []Value) (Value, error) {
+		if i == n {
+			return r.CreateIterResultObject(Undefined(), true), nil
+		}
+		i++
+		return r.CreateIterResultObject(Number(float64(i-1)), false), nil
+	})
+	r.defineMethod(it, "return", 0, func(this Value, args []Value) (Value, error) {
+		*closed++
+		return result, nil
+	})
+	return it
+}
+
+func TestIterableToList(t *testing.T) {
+	r := NewRealm()
+	list, err := r.IterableToList(Str("a\U0001F600b"))
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, len(list), 3)
+	test.AssertEqual(t, list[1].String(), "\U0001F600")
+
+	f := r.NewFunction(thisBody, r.GlobalEnv, ThisModeStrict)
+	args := r.CreateMappedArgumentsObject(f, nil, []Value{Number(1), Number(2)}, r.GlobalEnv)
+	list, _ = r.IterableToList(args.Value())
+	test.AssertEqual(t, len(list), 2)
+	test.AssertEqual(t, list[1].AsNumber(), 2.0)
+
+	// Iterators are iterable and return themselves
+	closed := 0
+	it := newCountingIterator(r, 3, &closed, NewObject(nil).Value())
+	list, _ = r.IterableToList(it.Value())
+	test.AssertEqual(t, len(list), 3)
+	test.AssertEqual(t, closed, 0)
+
+	_, err = r.IterableToList(Number(1))
+	test.AssertEqual(t, errorType(err), TypeError)
+	_, err = r.IterableToList(NewObject(nil).Value())
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// The next method rejects other kinds of iterators
+	strIter, _ := r.GetIterator(Str("x"))
+	arrIter, _ := r.GetIterator(args.Value())
+	_, err = Call(arrIter.NextMethod, strIter.Iterator.Value())
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// Iterators that threw are exhausted
+	thrower := r.NewBuiltinFunction("", 0, func(this Value, args []Value) (Value, error) {
+		return Undefined(), throwTypeError("bad")
+	})
+	arrayLike := NewObject(nil)
+	CreateDataProperty(arrayLike, StringKey("length"), Number(2))
+	arrayLike.DefineOwnProperty(StringKey("0"), AccessorDescriptor(thrower.Value(), Undefined(), false, true))
+	it = r.CreateArrayIterator(arrayLike)
+	_, err = r.invoke(it.Value(), "next")
+	test.AssertEqual(t, errorType(err), TypeError)
+	result, err := r.invoke(it.Value(), "next")
+	test.AssertEqual(t, err, nil)
+	v, _ := Get(result.AsObject(), StringKey("value"))
+	test.AssertEqual(t, v.IsUndefined(), true)
+	done, _ := Get(result.AsObject(), StringKey("done"))
+	test.AssertEqual(t, done.AsBool(), true)
+}
+
+func TestGetV(t *testing.T) {
+	r := NewRealm()
+	v, _ := r.GetV(Str("ab"), StringKey("length"))
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	v, _ = r.GetV(Str("ab"), StringKey("1"))
+	test.AssertEqual(t, v.String(), "b")
+
+	// Getters on the prototype see the primitive as their receiver
+	getter := r.NewBuiltinFunction("", 0, func(this Value, args []Value) (Value, error) {
+		return this, nil
+	})
+	r.NumberPrototype.DefineOwnProperty(StringKey("self"), AccessorDescriptor(getter.Value(), Undefined(), false, true))
+	v, _ = r.GetV(Number(3), StringKey("self"))
+	test.AssertEqual(t, v.AsNumber(), 3.0)
+	_, err := r.GetV(Null(), StringKey("x"))
+	test.AssertEqual(t, errorType(err), TypeError)
+}
+
+func TestIteratorClose(t *testing.T) {
+	r := NewRealm()
+	closed := 0
+
+	// for (const x of it) { if (x == 1) break; }
+	rec, _ := r.GetIterator(newCountingIterator(r, 3, &closed, NewObject(nil).Value()).Value())
+	for {
+		v, done, err := IteratorStepValue(rec)
+		test.AssertEqual(t, err, nil)
+		if done {
+			break
+		}
+		if v.AsNumber() == 1 {
+			test.AssertEqual(t, IteratorClose(rec, nil), nil)
+			break
+		}
+	}
+	test.AssertEqual(t, closed, 1)
+
+	// Exhausted iterators are not closed
+	rec, _ = r.GetIterator(newCountingIterator(r, 0, &closed, Undefined()).Value())
+	_, done, _ := IteratorStepValue(rec)
+	test.AssertEqual(t, done, true)
+	test.AssertEqual(t, rec.Done, true)
+
+	// return must return an object, unless the loop body threw
+	rec, _ = r.GetIterator(newCountingIterator(r, 3, &closed, Number(1)).Value())
+	test.AssertEqual(t, errorType(IteratorClose(rec, nil)), TypeError)
+	thrown := Throw(Str("body"))
+	test.AssertEqual(t, IteratorClose(rec, thrown), error(thrown))
+	test.AssertEqual(t, closed, 3)
+}
@@ -0,0 +1,54 @@
+package runtime
+
+// Job is a queued unit of work, such as the reaction to a settled
+// promise. It runs after the script that queued it has returned.
+type Job func() error
+
+// RejectionOperation is passed to a RejectionTracker.
+type RejectionOperation uint8
+
+// Definition of rejection operations
+const (
+	RejectionReject RejectionOperation = iota // rejected without any handler
+	RejectionHandle                           // a handler was added after an unhandled rejection
+)
+
+// RejectionTracker is notified of unhandled promise rejections, as
+// HostPromiseRejectionTracker. A promise reported with RejectionReject
+// that is not reported with RejectionHandle by the time the job queue
+// is drained is an unhandled rejection.
+type RejectionTracker func(promise *Object, op RejectionOperation)
+
+// HostEnqueuePromiseJob queues job to run once the job queue is drained.
+func (r *Realm) HostEnqueuePromiseJob(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// RunJobs runs queued jobs in order, including those they queue, until
+// the queue is empty. Embedders call it when script evaluation returns,
+// which makes them control when microtasks run. If a job throws, its
+// error is returned and the remaining jobs stay queued.
+func (r *Realm) RunJobs() error {
+	for len(r.jobs) > 0 {
+		job := r.jobs[0]
+		r.jobs[0] = nil
+		r.jobs = r.jobs[1:]
+		if err := job(); err != nil {
+			return err
+		}
+	}
+	r.jobs = nil
+	return nil
+}
+
+// PendingJobs returns the number of queued jobs.
+func (r *Realm) PendingJobs() int {
+	return len(r.jobs)
+}
+
+// trackRejection calls the rejection tracker, if any.
+func (r *Realm) trackRejection(promise *Object, op RejectionOperation) {
+	if r.RejectionTracker != nil {
+		r.RejectionTracker(promise, op)
+	}
+}
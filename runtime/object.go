@@ -0,0 +1,488 @@
+package runtime
+
+import (
+	"sort"
+	"strconv"
+)
+
+// NativeFunction implements the [[Call]] internal method of a function
+// object provided by the host.
+type NativeFunction func(this Value, args []Value) (Value, error)
+
+// ConstructFunction implements the [[Construct]] internal method of a
+// function object. newTarget is the constructor new was applied to.
+type ConstructFunction func(args []Value, newTarget *Object) (Value, error)
+
+// Object is an ECMAScript object.
+type Object struct {
+	methods    internalMethods
+	proto      *Object
+	extensible bool
+	shape      *shape
+	slots      []Value           // own property values, indexed by shape
+	rootShape  *shape            // root shape of objects inheriting from this one
+	call       NativeFunction    // non-nil for callable objects
+	construct  ConstructFunction // non-nil for constructors
+	internal   interface{}       // internal slots of the kind of object, such as *function
+	private    []*PrivateElement // [[PrivateElements]], which objects of any kind may have
+}
+
+// function returns the slots of an ECMAScript function object, or nil.
+func (o *Object) function() *function {
+	f, _ := o.internal.(*function)
+	return f
+}
+
+// bound returns the slots of a bound function exotic object, or nil.
+func (o *Object) bound() *boundFunction {
+	b, _ := o.internal.(*boundFunction)
+	return b
+}
+
+// primitive returns the [[BooleanData]], [[NumberData]] etc. of a
+// wrapper object, or undefined.
+func (o *Object) primitive() Value {
+	v, _ := o.internal.(Value)
+	return v
+}
+
+// generator returns the slots of a generator object, or nil.
+func (o *Object) generator() *generator {
+	g, _ := o.internal.(*generator)
+	return g
+}
+
+// iterator returns the slots of an array or string iterator, or nil.
+func (o *Object) iterator() *builtinIterator {
+	it, _ := o.internal.(*builtinIterator)
+	return it
+}
+
+// promise returns the slots of a promise object, or nil.
+func (o *Object) promise() *promise {
+	p, _ := o.internal.(*promise)
+	return p
+}
+
+// asyncGen returns the slots of an async generator object, or nil.
+func (o *Object) asyncGen() *asyncGenerator {
+	g, _ := o.internal.(*asyncGenerator)
+	return g
+}
+
+// syncIter returns the [[SyncIteratorRecord]] of an async-from-sync
+// iterator, or nil.
+func (o *Object) syncIter() *IteratorRecord {
+	rec, _ := o.internal.(*IteratorRecord)
+	return rec
+}
+
+// internalMethods are the property-related internal methods of an
+// object. Exotic objects embed ordinary and override some of them.
+type internalMethods interface {
+	getOwnProperty(o *Object, key PropertyKey) (PropertyDescriptor, bool)
+	defineOwnProperty(o *Object, key PropertyKey, desc PropertyDescriptor) bool
+	hasProperty(o *Object, key PropertyKey) bool
+	get(o *Object, key PropertyKey, receiver Value) (Value, error)
+	set(o *Object, key PropertyKey, v Value, receiver Value) (bool, error)
+	delete(o *Object, key PropertyKey) bool
+	ownPropertyKeys(o *Object) []PropertyKey
+}
+
+// ordinary implements the internal methods of ordinary objects.
+type ordinary struct{}
+
+// NewObject creates an ordinary, extensible object whose prototype is
+// proto, which may be nil.
+func NewObject(proto *Object) *Object {
+	return &Object{
+		methods:    ordinary{},
+		proto:      proto,
+		extensible: true,
+		shape:      rootShapeFor(proto),
+	}
+}
+
+// newExoticObject creates an object with the internal methods m. Its
+// shapes are never shared with ordinary objects, so inline caches
+// filled by ordinary objects cannot match it.
+func newExoticObject(m internalMethods, proto *Object) *Object {
+	return &Object{
+		methods:    m,
+		proto:      proto,
+		extensible: true,
+		shape:      &shape{},
+	}
+}
+
+// NewNativeFunction creates a callable object backed by fn.
+func NewNativeFunction(fn NativeFunction) *Object {
+	o := NewObject(nil)
+	o.call = fn
+	return o
+}
+
+// GetPrototypeOf implements [[GetPrototypeOf]].
+func (o *Object) GetPrototypeOf() *Object {
+	return o.proto
+}
+
+// SetPrototypeOf implements [[SetPrototypeOf]]. It reports false if o is
+// not extensible or if the change would create a prototype cycle.
+func (o *Object) SetPrototypeOf(proto *Object) bool {
+	if proto == o.proto {
+		return true
+	}
+	if !o.extensible {
+		return false
+	}
+	for p := proto; p != nil; p = p.proto {
+		if p == o {
+			return false
+		}
+	}
+	o.changePrototype(proto)
+	return true
+}
+
+// IsExtensible implements [[IsExtensible]].
+func (o *Object) IsExtensible() bool {
+	return o.extensible
+}
+
+// PreventExtensions implements [[PreventExtensions]].
+func (o *Object) PreventExtensions() bool {
+	o.extensible = false
+	return true
+}
+
+// GetOwnProperty implements [[GetOwnProperty]]. It reports false if o
+// has no own property key.
+func (o *Object) GetOwnProperty(key PropertyKey) (PropertyDescriptor, bool) {
+	return o.methods.getOwnProperty(o, key)
+}
+
+// DefineOwnProperty implements [[DefineOwnProperty]].
+func (o *Object) DefineOwnProperty(key PropertyKey, desc PropertyDescriptor) bool {
+	return o.methods.defineOwnProperty(o, key, desc)
+}
+
+// HasProperty implements [[HasProperty]].
+func (o *Object) HasProperty(key PropertyKey) bool {
+	return o.methods.hasProperty(o, key)
+}
+
+// Get implements [[Get]]: it returns the value of the property key,
+// using receiver as the this value for getters.
+func (o *Object) Get(key PropertyKey, receiver Value) (Value, error) {
+	return o.methods.get(o, key, receiver)
+}
+
+// Set implements [[Set]], using receiver as the this value for setters.
+func (o *Object) Set(key PropertyKey, v Value, receiver Value) (bool, error) {
+	return o.methods.set(o, key, v, receiver)
+}
+
+// Delete implements [[Delete]].
+func (o *Object) Delete(key PropertyKey) bool {
+	return o.methods.delete(o, key)
+}
+
+// OwnPropertyKeys implements [[OwnPropertyKeys]]: array indices in
+// ascending order, then strings and symbols in creation order.
+func (o *Object) OwnPropertyKeys() []PropertyKey {
+	return o.methods.ownPropertyKeys(o)
+}
+
+func (ordinary) getOwnProperty(o *Object, key PropertyKey) (PropertyDescriptor, bool) {
+	i := o.shape.lookup(key)
+	if i < 0 {
+		return PropertyDescriptor{}, false
+	}
+	return storedDescriptor(o.shape.attrs[i], o.slots[i]), true
+}
+
+func (ordinary) defineOwnProperty(o *Object, key PropertyKey, desc PropertyDescriptor) bool {
+	current, ok := o.GetOwnProperty(key)
+	return validateAndApplyPropertyDescriptor(o, key, o.extensible, desc, current, ok)
+}
+
+func (ordinary) hasProperty(o *Object, key PropertyKey) bool {
+	if _, ok := o.GetOwnProperty(key); ok {
+		return true
+	}
+	if o.proto == nil {
+		return false
+	}
+	return o.proto.HasProperty(key)
+}
+
+func (ordinary) get(o *Object, key PropertyKey, receiver Value) (Value, error) {
+	desc, ok := o.GetOwnProperty(key)
+	if !ok {
+		if o.proto == nil {
+			return Undefined(), nil
+		}
+		return o.proto.Get(key, receiver)
+	}
+	if desc.IsDataDescriptor() {
+		return desc.Value, nil
+	}
+	if desc.Get.IsUndefined() {
+		return Undefined(), nil
+	}
+	return Call(desc.Get, receiver)
+}
+
+func (ordinary) set(o *Object, key PropertyKey, v Value, receiver Value) (bool, error) {
+	desc, ok := o.GetOwnProperty(key)
+	return ordinarySetWithOwnDescriptor(o, key, v, receiver, desc, ok)
+}
+
+// ordinarySetWithOwnDescriptor implements OrdinarySetWithOwnDescriptor.
+func ordinarySetWithOwnDescriptor(o *Object, key PropertyKey, v, receiver Value, desc PropertyDescriptor, ok bool) (bool, error) {
+	if !ok {
+		if o.proto != nil {
+			return o.proto.Set(key, v, receiver)
+		}
+		desc = DataDescriptor(Undefined(), true, true, true)
+	}
+
+	if desc.IsDataDescriptor() {
+		if !desc.Writable {
+			return false, nil
+		}
+		r := receiver.AsObject()
+		if r == nil {
+			return false, nil
+		}
+		existing, ok := r.GetOwnProperty(key)
+		if !ok {
+			return CreateDataProperty(r, key, v), nil
+		}
+		if existing.IsAccessorDescriptor() || !existing.Writable {
+			return false, nil
+		}
+		return r.DefineOwnProperty(key, PropertyDescriptor{Value: v, Fields: HasValue}), nil
+	}
+
+	if desc.Set.IsUndefined() {
+		return false, nil
+	}
+	_, err := Call(desc.Set, receiver, v)
+	return err == nil, err
+}
+
+func (ordinary) delete(o *Object, key PropertyKey) bool {
+	desc, ok := o.GetOwnProperty(key)
+	if !ok {
+		return true
+	}
+	if desc.Configurable {
+		o.removeProperty(key)
+		return true
+	}
+	return false
+}
+
+func (ordinary) ownPropertyKeys(o *Object) []PropertyKey {
+	return orderPropertyKeys(o.shape.keys)
+}
+
+// orderPropertyKeys sorts keys in the order mandated for
+// [[OwnPropertyKeys]] without modifying the keys slice.
+func orderPropertyKeys(keys []PropertyKey) []PropertyKey {
+	var indices []uint32
+	var strs, syms []PropertyKey
+	for _, k := range keys {
+		if i, ok := k.arrayIndex(); ok {
+			indices = append(indices, i)
+		} else if k.IsSymbol() {
+			syms = append(syms, k)
+		} else {
+			strs = append(strs, k)
+		}
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	ordered := make([]PropertyKey, 0, len(keys))
+	for _, i := range indices {
+		ordered = append(ordered, PropertyKey{str: strconv.FormatUint(uint64(i), 10)})
+	}
+	ordered = append(ordered, strs...)
+	return append(ordered, syms...)
+}
+
+// arrayIndex reports whether k is an array index: the canonical string
+// of an integer in the range [0, 2^32-2].
+func (k PropertyKey) arrayIndex() (uint32, bool) {
+	s := k.str
+	if k.sym != nil || s == "" || len(s) > 10 || (s[0] == '0' && len(s) > 1) {
+		return 0, false
+	}
+	n := uint64(0)
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + uint64(s[i]-'0')
+	}
+	if n >= 1<<32-1 {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// IsCallable reports whether v is an object with a [[Call]] method.
+func IsCallable(v Value) bool {
+	o := v.AsObject()
+	return o != nil && o.call != nil
+}
+
+// Call calls the function f with the given this value and arguments.
+func Call(f Value, this Value, args ...Value) (Value, error) {
+	if !IsCallable(f) {
+		return Undefined(), throwTypeError("%s is not a function", f)
+	}
+	return f.AsObject().call(this, args)
+}
+
+// IsConstructor reports whether v is an object with a [[Construct]] method.
+func IsConstructor(v Value) bool {
+	o := v.AsObject()
+	return o != nil && o.construct != nil
+}
+
+// Construct calls the constructor f as by new. newTarget defaults to f.
+func Construct(f *Object, args []Value, newTarget *Object) (Value, error) {
+	if f.construct == nil {
+		return Undefined(), throwTypeError("%s is not a constructor", f.Value())
+	}
+	if newTarget == nil {
+		newTarget = f
+	}
+	return f.construct(args, newTarget)
+}
+
+// Get returns the value of the property key of o.
+func Get(o *Object, key PropertyKey) (Value, error) {
+	return o.Get(key, o.Value())
+}
+
+// Set sets the property key of o to v. If throw is true, a failed
+// assignment is reported as a TypeError.
+func Set(o *Object, key PropertyKey, v Value, throw bool) error {
+	ok, err := o.Set(key, v, o.Value())
+	if err != nil {
+		return err
+	}
+	if !ok && throw {
+		return throwTypeError("Cannot assign to read only property '%s'", key)
+	}
+	return nil
+}
+
+// HasOwnProperty reports whether o has an own property key.
+func HasOwnProperty(o *Object, key PropertyKey) bool {
+	_, ok := o.GetOwnProperty(key)
+	return ok
+}
+
+// CreateDataProperty creates or replaces the own property key of o with
+// a writable, enumerable and configurable data property.
+func CreateDataProperty(o *Object, key PropertyKey, v Value) bool {
+	return o.DefineOwnProperty(key, DataDescriptor(v, true, true, true))
+}
+
+// CreateDataPropertyOrThrow is CreateDataProperty, reporting failure as
+// a TypeError.
+func CreateDataPropertyOrThrow(o *Object, key PropertyKey, v Value) error {
+	if !CreateDataProperty(o, key, v) {
+		return throwTypeError("Cannot define property '%s'", key)
+	}
+	return nil
+}
+
+// DefinePropertyOrThrow calls [[DefineOwnProperty]], reporting failure
+// as a TypeError.
+func DefinePropertyOrThrow(o *Object, key PropertyKey, desc PropertyDescriptor) error {
+	if !o.DefineOwnProperty(key, desc) {
+		return throwTypeError("Cannot redefine property '%s'", key)
+	}
+	return nil
+}
+
+// DeletePropertyOrThrow calls [[Delete]], reporting failure as a TypeError.
+func DeletePropertyOrThrow(o *Object, key PropertyKey) error {
+	if !o.Delete(key) {
+		return throwTypeError("Cannot delete property '%s'", key)
+	}
+	return nil
+}
+
+// getMethod returns the function stored under key, or undefined if
+// there is none.
+func getMethod(o *Object, key PropertyKey) (Value, error) {
+	f, err := Get(o, key)
+	if err != nil || f.IsNullish() {
+		return Undefined(), err
+	}
+	if !IsCallable(f) {
+		return Undefined(), throwTypeError("%s is not a function", key)
+	}
+	return f, nil
+}
+
+// IntegrityLevel is the level passed to SetIntegrityLevel.
+type IntegrityLevel uint8
+
+// Definition of integrity levels
+const (
+	Sealed IntegrityLevel = iota
+	Frozen
+)
+
+// SetIntegrityLevel prevents extensions to o and makes all its own
+// properties non-configurable, and also read-only when level is Frozen.
+// This is the common part of Object.seal and Object.freeze.
+func SetIntegrityLevel(o *Object, level IntegrityLevel) bool {
+	if !o.PreventExtensions() {
+		return false
+	}
+	for _, key := range o.OwnPropertyKeys() {
+		desc := PropertyDescriptor{Configurable: false, Fields: HasConfigurable}
+		if level == Frozen {
+			current, ok := o.GetOwnProperty(key)
+			if !ok {
+				continue
+			}
+			if current.IsDataDescriptor() {
+				desc.Fields |= HasWritable
+			}
+		}
+		if !o.DefineOwnProperty(key, desc) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestIntegrityLevel reports whether o is sealed or frozen.
+func TestIntegrityLevel(o *Object, level IntegrityLevel) bool {
+	if o.IsExtensible() {
+		return false
+	}
+	for _, key := range o.OwnPropertyKeys() {
+		desc, ok := o.GetOwnProperty(key)
+		if !ok {
+			continue
+		}
+		if desc.Configurable {
+			return false
+		}
+		if level == Frozen && desc.IsDataDescriptor() && desc.Writable {
+			return false
+		}
+	}
+	return true
+}
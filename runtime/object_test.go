@@ -0,0 +1,178 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func TestOwnPropertyKeys(t *testing.T) {
+	o := NewObject(nil)
+	sym1, sym2 := NewSymbol(nil), NewSymbol(nil)
+	keys := []PropertyKey{
+		StringKey("b"), StringKey("10"), SymbolKey(sym2), StringKey("a"), StringKey("2"),
+		StringKey("4294967295"), StringKey("01"), SymbolKey(sym1), StringKey("4294967294"),
+	}
+	for _, k := range keys {
+		CreateDataProperty(o, k, Undefined())
+	}
+	o.Delete(StringKey("a"))
+
+	expected := []PropertyKey{
+		StringKey("2"), StringKey("10"), StringKey("4294967294"),
+		StringKey("b"), StringKey("4294967295"), StringKey("01"),
+		SymbolKey(sym2), SymbolKey(sym1),
+	}
+	out := o.OwnPropertyKeys()
+	test.AssertEqual(t, len(out), len(expected))
+	for i, k := range expected {
+		test.AssertEqual(t, out[i], k)
+	}
+}
+
+func TestDefineOwnProperty(t *testing.T) {
+	o := NewObject(nil)
+	key := StringKey("x")
+
+	// Absent fields default to false and undefined
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Value: Number(1), Fields: HasValue}), true)
+	desc, ok := o.GetOwnProperty(key)
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, desc.Writable || desc.Enumerable || desc.Configurable, false)
+
+	// Non-configurable, non-writable properties only accept no-op changes
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Value: Number(1), Fields: HasValue}), true)
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Value: Number(2), Fields: HasValue}), false)
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Writable: true, Fields: HasWritable}), false)
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Enumerable: true, Fields: HasEnumerable}), false)
+	test.AssertEqual(t, o.DefineOwnProperty(key, AccessorDescriptor(Undefined(), Undefined(), false, false)), false)
+	test.AssertEqual(t, o.Delete(key), false)
+
+	// Configurable properties can change kind, keeping shared attributes
+	key = StringKey("y")
+	getter := NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		return Str("got"), nil
+	}).Value()
+	test.AssertEqual(t, o.DefineOwnProperty(key, DataDescriptor(Number(1), true, true, true)), true)
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Get: getter, Fields: HasGet}), true)
+	desc, _ = o.GetOwnProperty(key)
+	test.AssertEqual(t, desc.IsAccessorDescriptor(), true)
+	test.AssertEqual(t, desc.Enumerable && desc.Configurable, true)
+	test.AssertEqual(t, desc.Set.IsUndefined(), true)
+
+	v, err := Get(o, key)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.String(), "got")
+
+	test.AssertEqual(t, o.DefineOwnProperty(key, PropertyDescriptor{Writable: true, Fields: HasWritable}), true)
+	desc, _ = o.GetOwnProperty(key)
+	test.AssertEqual(t, desc.IsDataDescriptor(), true)
+	test.AssertEqual(t, desc.Value.IsUndefined(), true)
+	test.AssertEqual(t, o.Delete(key), true)
+	test.AssertEqual(t, HasOwnProperty(o, key), false)
+
+	// Non-extensible objects reject new properties
+	o.PreventExtensions()
+	test.AssertEqual(t, CreateDataProperty(o, StringKey("z"), Null()), false)
+	test.AssertEqual(t, CreateDataPropertyOrThrow(o, StringKey("z"), Null()).(*Exception).Type, TypeError)
+}
+
+func TestPrototypeChain(t *testing.T) {
+	proto := NewObject(nil)
+	o := NewObject(proto)
+
+	CreateDataProperty(proto, StringKey("inherited"), Number(1))
+	v, _ := Get(o, StringKey("inherited"))
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	test.AssertEqual(t, o.HasProperty(StringKey("inherited")), true)
+	test.AssertEqual(t, HasOwnProperty(o, StringKey("inherited")), false)
+
+	// Assignment shadows inherited data properties
+	test.AssertEqual(t, Set(o, StringKey("inherited"), Number(2), true), nil)
+	v, _ = Get(o, StringKey("inherited"))
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	v, _ = Get(proto, StringKey("inherited"))
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+
+	// Inherited read-only properties cannot be shadowed by assignment
+	proto.DefineOwnProperty(StringKey("ro"), DataDescriptor(Number(1), false, true, true))
+	test.AssertEqual(t, Set(o, StringKey("ro"), Number(2), false), nil)
+	test.AssertEqual(t, Set(o, StringKey("ro"), Number(2), true).(*Exception).Type, TypeError)
+	test.AssertEqual(t, HasOwnProperty(o, StringKey("ro")), false)
+
+	// Inherited setters are called with the receiver as this
+	var this Value
+	setter := NewNativeFunction(func(t Value, args []Value) (Value, error) {
+		this = t
+		return Undefined(), nil
+	}).Value()
+	proto.DefineOwnProperty(StringKey("acc"), AccessorDescriptor(Undefined(), setter, true, true))
+	test.AssertEqual(t, Set(o, StringKey("acc"), Number(3), true), nil)
+	test.AssertEqual(t, this.AsObject(), o)
+	test.AssertEqual(t, HasOwnProperty(o, StringKey("acc")), false)
+
+	// Prototype cycles are rejected
+	test.AssertEqual(t, proto.SetPrototypeOf(o), false)
+	test.AssertEqual(t, proto.SetPrototypeOf(proto), false)
+	test.AssertEqual(t, o.SetPrototypeOf(nil), true)
+	test.AssertEqual(t, o.HasProperty(StringKey("inherited")), true)
+	test.AssertEqual(t, o.HasProperty(StringKey("ro")), false)
+
+	o.PreventExtensions()
+	test.AssertEqual(t, o.SetPrototypeOf(proto), false)
+	test.AssertEqual(t, o.SetPrototypeOf(nil), true)
+}
+
+func TestSealFreeze(t *testing.T) {
+	o := NewObject(nil)
+	CreateDataProperty(o, StringKey("data"), Number(1))
+	o.DefineOwnProperty(StringKey("acc"), AccessorDescriptor(Undefined(), Undefined(), true, true))
+
+	test.AssertEqual(t, TestIntegrityLevel(o, Sealed), false)
+	test.AssertEqual(t, SetIntegrityLevel(o, Sealed), true)
+	test.AssertEqual(t, TestIntegrityLevel(o, Sealed), true)
+	test.AssertEqual(t, TestIntegrityLevel(o, Frozen), false)
+	test.AssertEqual(t, DeletePropertyOrThrow(o, StringKey("data")).(*Exception).Type, TypeError)
+	test.AssertEqual(t, Set(o, StringKey("data"), Number(2), true), nil)
+
+	test.AssertEqual(t, SetIntegrityLevel(o, Frozen), true)
+	test.AssertEqual(t, TestIntegrityLevel(o, Frozen), true)
+	test.AssertEqual(t, Set(o, StringKey("data"), Number(3), true).(*Exception).Type, TypeError)
+	v, _ := Get(o, StringKey("data"))
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+
+	desc, _ := o.GetOwnProperty(StringKey("acc"))
+	test.AssertEqual(t, desc.IsAccessorDescriptor(), true)
+	test.AssertEqual(t, desc.Configurable, false)
+}
+
+func TestStringObject(t *testing.T) {
+	r := NewRealm()
+	o, err := r.ToObject(Str("ab"))
+	test.AssertEqual(t, err, nil)
+
+	// Code units and length are read-only own properties
+	desc, ok := o.GetOwnProperty(StringKey("1"))
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, desc.Value.String(), "b")
+	test.AssertEqual(t, desc.Enumerable, true)
+	test.AssertEqual(t, desc.Writable, false)
+	_, ok = o.GetOwnProperty(StringKey("2"))
+	test.AssertEqual(t, ok, false)
+	length, _ := Get(o, StringKey("length"))
+	test.AssertEqual(t, length.AsNumber(), 2.0)
+	test.AssertEqual(t, errorType(Set(o, StringKey("0"), Str("x"), true)), TypeError)
+	test.AssertEqual(t, o.Delete(StringKey("length")), false)
+	test.AssertEqual(t, o.DefineOwnProperty(StringKey("0"), DataDescriptor(Str("a"), false, true, false)), true)
+	test.AssertEqual(t, o.DefineOwnProperty(StringKey("0"), DataDescriptor(Str("x"), false, true, false)), false)
+
+	// Other properties are ordinary
+	test.AssertEqual(t, CreateDataProperty(o, StringKey("5"), Null()), true)
+	test.AssertEqual(t, CreateDataProperty(o, StringKey("x"), Null()), true)
+	expected := []string{"0", "1", "5", "length", "x"}
+	keys := o.OwnPropertyKeys()
+	test.AssertEqual(t, len(keys), len(expected))
+	for i, k := range expected {
+		test.AssertEqual(t, keys[i], StringKey(k))
+	}
+}
@@ -0,0 +1,479 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+)
+
+// Operator is a binary operator of the expression grammar.
+type Operator uint8
+
+// Definition of binary operators
+const (
+	OpAdd Operator = iota
+	OpSub
+	OpMul
+	OpDiv
+	OpRem
+	OpExp
+	OpShl
+	OpShr
+	OpUShr
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+	OpEqual
+	OpNotEqual
+	OpStrictEqual
+	OpStrictNotEqual
+	OpInstanceof
+	OpIn
+)
+
+var operatorNames = [...]string{
+	OpAdd:            "+",
+	OpSub:            "-",
+	OpMul:            "*",
+	OpDiv:            "/",
+	OpRem:            "%",
+	OpExp:            "**",
+	OpShl:            "<<",
+	OpShr:            ">>",
+	OpUShr:           ">>>",
+	OpBitAnd:         "&",
+	OpBitOr:          "|",
+	OpBitXor:         "^",
+	OpLess:           "<",
+	OpGreater:        ">",
+	OpLessEqual:      "<=",
+	OpGreaterEqual:   ">=",
+	OpEqual:          "==",
+	OpNotEqual:       "!=",
+	OpStrictEqual:    "===",
+	OpStrictNotEqual: "!==",
+	OpInstanceof:     "instanceof",
+	OpIn:             "in",
+}
+
+func (op Operator) String() string {
+	return operatorNames[op]
+}
+
+// BinaryOperator evaluates x op y, where x and y are the values of the
+// already evaluated operands.
+func BinaryOperator(op Operator, x, y Value) (Value, error) {
+	switch op {
+	case OpLess, OpGreater, OpLessEqual, OpGreaterEqual:
+		return relationalOperator(op, x, y)
+	case OpEqual, OpNotEqual:
+		eq, err := IsLooselyEqual(x, y)
+		return Bool(eq == (op == OpEqual)), err
+	case OpStrictEqual:
+		return Bool(IsStrictlyEqual(x, y)), nil
+	case OpStrictNotEqual:
+		return Bool(!IsStrictlyEqual(x, y)), nil
+	case OpInstanceof:
+		ok, err := InstanceofOperator(x, y)
+		return Bool(ok), err
+	case OpIn:
+		o := y.AsObject()
+		if o == nil {
+			return Undefined(), throwTypeError("Cannot use 'in' operator to search for '%s' in %s", x, y)
+		}
+		key, err := ToPropertyKey(x)
+		if err != nil {
+			return Undefined(), err
+		}
+		return Bool(o.HasProperty(key)), nil
+	}
+	return applyStringOrNumericBinaryOperator(x, op, y)
+}
+
+// applyStringOrNumericBinaryOperator implements
+// ApplyStringOrNumericBinaryOperator.
+func applyStringOrNumericBinaryOperator(x Value, op Operator, y Value) (Value, error) {
+	if op == OpAdd {
+		lprim, err := ToPrimitive(x, HintDefault)
+		if err != nil {
+			return Undefined(), err
+		}
+		rprim, err := ToPrimitive(y, HintDefault)
+		if err != nil {
+			return Undefined(), err
+		}
+		if lprim.kind == KindString || rprim.kind == KindString {
+			lstr, err := ToString(lprim)
+			if err != nil {
+				return Undefined(), err
+			}
+			rstr, err := ToString(rprim)
+			if err != nil {
+				return Undefined(), err
+			}
+			return lstr.Concat(rstr).Value(), nil
+		}
+		x, y = lprim, rprim
+	}
+
+	lnum, err := ToNumeric(x)
+	if err != nil {
+		return Undefined(), err
+	}
+	rnum, err := ToNumeric(y)
+	if err != nil {
+		return Undefined(), err
+	}
+	if lnum.kind != rnum.kind {
+		return Undefined(), throwTypeError("Cannot mix BigInt and other types, use explicit conversions")
+	}
+	if lnum.kind == KindBigInt {
+		return bigIntOperator(lnum.AsBigInt(), op, rnum.AsBigInt())
+	}
+	return Number(numberOperator(lnum.num, op, rnum.num)), nil
+}
+
+// numberOperator applies an arithmetic, shift or bitwise operator to
+// two Numbers.
+func numberOperator(x float64, op Operator, y float64) float64 {
+	switch op {
+	case OpAdd:
+		return x + y
+	case OpSub:
+		return x - y
+	case OpMul:
+		return x * y
+	case OpDiv:
+		return x / y
+	case OpRem:
+		return math.Mod(x, y)
+	case OpExp:
+		// Unlike math.Pow, 1 ** NaN and (±1) ** ±Infinity are NaN
+		if math.IsNaN(y) || math.IsInf(y, 0) && math.Abs(x) == 1 {
+			return math.NaN()
+		}
+		return math.Pow(x, y)
+	case OpShl:
+		return float64(ToInt32(x) << (ToUint32(y) & 31))
+	case OpShr:
+		return float64(ToInt32(x) >> (ToUint32(y) & 31))
+	case OpUShr:
+		return float64(ToUint32(x) >> (ToUint32(y) & 31))
+	case OpBitAnd:
+		return float64(ToInt32(x) & ToInt32(y))
+	case OpBitOr:
+		return float64(ToInt32(x) | ToInt32(y))
+	case OpBitXor:
+		return float64(ToInt32(x) ^ ToInt32(y))
+	}
+	panic("runtime: invalid numeric operator " + op.String())
+}
+
+// maxBigIntShift bounds shift counts so that results stay allocatable.
+const maxBigIntShift = 1 << 30
+
+// bigIntOperator applies an arithmetic, shift or bitwise operator to
+// two BigInts.
+func bigIntOperator(x *big.Int, op Operator, y *big.Int) (Value, error) {
+	z := new(big.Int)
+	switch op {
+	case OpAdd:
+		z.Add(x, y)
+	case OpSub:
+		z.Sub(x, y)
+	case OpMul:
+		z.Mul(x, y)
+	case OpDiv, OpRem:
+		if y.Sign() == 0 {
+			return Undefined(), throwError(RangeError, "Division by zero")
+		}
+		if op == OpDiv {
+			z.Quo(x, y)
+		} else {
+			z.Rem(x, y)
+		}
+	case OpExp:
+		if y.Sign() < 0 {
+			return Undefined(), throwError(RangeError, "Exponent must be non-negative")
+		}
+		if x.CmpAbs(big.NewInt(1)) > 0 && (!y.IsInt64() || y.Int64() > maxBigIntShift) {
+			return Undefined(), throwError(RangeError, "Maximum BigInt size exceeded")
+		}
+		z.Exp(x, y, nil)
+	case OpShl, OpShr:
+		if !y.IsInt64() || y.Int64() > maxBigIntShift || y.Int64() < -maxBigIntShift {
+			if (op == OpShl) == (y.Sign() > 0) && x.Sign() != 0 {
+				return Undefined(), throwError(RangeError, "Maximum BigInt size exceeded")
+			}
+			// Shifting right by a huge amount leaves only the sign
+			if x.Sign() < 0 {
+				return BigInt(big.NewInt(-1)), nil
+			}
+			return BigInt(z), nil
+		}
+		n := y.Int64()
+		if op == OpShr {
+			n = -n
+		}
+		if n >= 0 {
+			z.Lsh(x, uint(n))
+		} else {
+			z.Rsh(x, uint(-n))
+		}
+	case OpUShr:
+		return Undefined(), throwTypeError("BigInts have no unsigned right shift, use >> instead")
+	case OpBitAnd:
+		z.And(x, y)
+	case OpBitOr:
+		z.Or(x, y)
+	case OpBitXor:
+		z.Xor(x, y)
+	}
+	return BigInt(z), nil
+}
+
+// relationalOperator evaluates <, >, <= and >=.
+func relationalOperator(op Operator, x, y Value) (Value, error) {
+	var lt, undef bool
+	var err error
+	switch op {
+	case OpLess:
+		lt, undef, err = isLessThan(x, y, true)
+		return Bool(lt && !undef), err
+	case OpGreater:
+		lt, undef, err = isLessThan(y, x, false)
+		return Bool(lt && !undef), err
+	case OpLessEqual:
+		lt, undef, err = isLessThan(y, x, false)
+	default:
+		lt, undef, err = isLessThan(x, y, true)
+	}
+	return Bool(!lt && !undef), err
+}
+
+// isLessThan implements IsLessThan. undef reports an undefined result,
+// which happens when a NaN is involved. leftFirst controls the order in
+// which the operands are converted to primitives.
+func isLessThan(x, y Value, leftFirst bool) (lt bool, undef bool, err error) {
+	var px, py Value
+	if leftFirst {
+		if px, err = ToPrimitive(x, HintNumber); err == nil {
+			py, err = ToPrimitive(y, HintNumber)
+		}
+	} else {
+		if py, err = ToPrimitive(y, HintNumber); err == nil {
+			px, err = ToPrimitive(x, HintNumber)
+		}
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	if px.kind == KindString && py.kind == KindString {
+		return px.AsString().Compare(py.AsString()) < 0, false, nil
+	}
+	if px.kind == KindBigInt && py.kind == KindString {
+		n, ok := stringToBigInt(py.AsString().String())
+		return ok && px.AsBigInt().Cmp(n) < 0, !ok, nil
+	}
+	if px.kind == KindString && py.kind == KindBigInt {
+		n, ok := stringToBigInt(px.AsString().String())
+		return ok && n.Cmp(py.AsBigInt()) < 0, !ok, nil
+	}
+
+	nx, err := ToNumeric(px)
+	if err != nil {
+		return false, false, err
+	}
+	ny, err := ToNumeric(py)
+	if err != nil {
+		return false, false, err
+	}
+
+	switch {
+	case nx.kind == KindNumber && ny.kind == KindNumber:
+		if math.IsNaN(nx.num) || math.IsNaN(ny.num) {
+			return false, true, nil
+		}
+		return nx.num < ny.num, false, nil
+	case nx.kind == KindBigInt && ny.kind == KindBigInt:
+		return nx.AsBigInt().Cmp(ny.AsBigInt()) < 0, false, nil
+	case nx.kind == KindBigInt:
+		c := compareBigIntNumber(nx.AsBigInt(), ny.num)
+		return c == -1, c == 2, nil
+	default:
+		c := compareBigIntNumber(ny.AsBigInt(), nx.num)
+		return c == 1, c == 2, nil
+	}
+}
+
+// InstanceofOperator implements the instanceof operator.
+func InstanceofOperator(v, target Value) (bool, error) {
+	t := target.AsObject()
+	if t == nil {
+		return false, throwTypeError("Right-hand side of 'instanceof' is not an object")
+	}
+	instOfHandler, err := getMethod(t, SymbolKey(SymbolHasInstance))
+	if err != nil {
+		return false, err
+	}
+	if !instOfHandler.IsUndefined() {
+		result, err := Call(instOfHandler, target, v)
+		return ToBoolean(result), err
+	}
+	if !IsCallable(target) {
+		return false, throwTypeError("Right-hand side of 'instanceof' is not callable")
+	}
+	return OrdinaryHasInstance(target, v)
+}
+
+// OrdinaryHasInstance reports whether the prototype property of the
+// function c is on the prototype chain of v.
+func OrdinaryHasInstance(c, v Value) (bool, error) {
+	if !IsCallable(c) {
+		return false, nil
+	}
+	if b := c.AsObject().bound(); b != nil {
+		return InstanceofOperator(v, b.target.Value())
+	}
+	o := v.AsObject()
+	if o == nil {
+		return false, nil
+	}
+	p, err := Get(c.AsObject(), StringKey("prototype"))
+	if err != nil {
+		return false, err
+	}
+	if !p.IsObject() {
+		return false, throwTypeError("Function has non-object prototype '%s' in instanceof check", p)
+	}
+	for o = o.GetPrototypeOf(); o != nil; o = o.GetPrototypeOf() {
+		if o == p.AsObject() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Typeof implements the typeof operator.
+func Typeof(v Value) string {
+	switch v.kind {
+	case KindUndefined:
+		return "undefined"
+	case KindNull:
+		return "object"
+	case KindBoolean:
+		return "boolean"
+	case KindNumber:
+		return "number"
+	case KindBigInt:
+		return "bigint"
+	case KindString:
+		return "string"
+	case KindSymbol:
+		return "symbol"
+	}
+	if IsCallable(v) {
+		return "function"
+	}
+	return "object"
+}
+
+// UnaryMinus implements the unary - operator.
+func UnaryMinus(v Value) (Value, error) {
+	n, err := ToNumeric(v)
+	if err != nil || n.kind == KindNumber {
+		return Number(-n.num), err
+	}
+	return BigInt(new(big.Int).Neg(n.AsBigInt())), nil
+}
+
+// BitwiseNot implements the ~ operator.
+func BitwiseNot(v Value) (Value, error) {
+	n, err := ToNumeric(v)
+	if err != nil {
+		return Undefined(), err
+	}
+	if n.kind == KindNumber {
+		return Number(float64(^ToInt32(n.num))), nil
+	}
+	return BigInt(new(big.Int).Not(n.AsBigInt())), nil
+}
+
+// Increment returns ToNumeric(v) plus delta, which is 1 or -1. It
+// implements the ++ and -- operators.
+func Increment(v Value, delta int) (Value, error) {
+	n, err := ToNumeric(v)
+	if err != nil {
+		return Undefined(), err
+	}
+	if n.kind == KindNumber {
+		return Number(n.num + float64(delta)), nil
+	}
+	return BigInt(new(big.Int).Add(n.AsBigInt(), big.NewInt(int64(delta)))), nil
+}
+
+// ToInt32 converts a Number to a signed 32-bit integer, modulo 2^32.
+func ToInt32(f float64) int32 {
+	return int32(ToUint32(f))
+}
+
+// ToUint32 converts a Number to an unsigned 32-bit integer, modulo 2^32.
+func ToUint32(f float64) uint32 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	f = math.Mod(math.Trunc(f), 1<<32)
+	if f < 0 {
+		f += 1 << 32
+	}
+	return uint32(f)
+}
+
+// ForInIterator enumerates the enumerable string-keyed properties of an
+// object and its prototypes in for-in order. Properties deleted before
+// being reached are skipped, and a property is never visited twice,
+// even if shadowed by a non-enumerable property.
+type ForInIterator struct {
+	obj     *Object
+	keys    []PropertyKey
+	started bool
+	visited map[PropertyKey]bool
+}
+
+// NewForInIterator creates an iterator over the properties of o.
+func NewForInIterator(o *Object) *ForInIterator {
+	return &ForInIterator{obj: o, visited: make(map[PropertyKey]bool)}
+}
+
+// Next returns the next property key as a String value. It reports
+// false when there are no more properties.
+func (it *ForInIterator) Next() (Value, bool) {
+	for it.obj != nil {
+		if !it.started {
+			it.keys = it.obj.OwnPropertyKeys()
+			it.started = true
+		}
+		for len(it.keys) > 0 {
+			key := it.keys[0]
+			it.keys = it.keys[1:]
+			if key.IsSymbol() || it.visited[key] {
+				continue
+			}
+			desc, ok := it.obj.GetOwnProperty(key)
+			if !ok {
+				continue
+			}
+			it.visited[key] = true
+			if desc.Enumerable {
+				return key.Value(), true
+			}
+		}
+		it.obj = it.obj.GetPrototypeOf()
+		it.started = false
+	}
+	return Undefined(), false
+}
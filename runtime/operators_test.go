@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func bigint(n int64) Value {
+	return BigInt(big.NewInt(n))
+}
+
+func TestBinaryOperator(t *testing.T) {
+	var calls []string
+	obj := newConvertible(Number(3), Str("obj"), &calls).Value()
+
+	tests := []struct {
+		x   Value
+		op  Operator
+		y   Value
+		out Value
+	}{
+		{Number(1), OpAdd, Number(2), Number(3)},
+		{Str("1"), OpAdd, Number(2), Str("12")},
+		{Number(1), OpAdd, Null(), Number(1)},
+		{Bool(true), OpAdd, Undefined(), Number(math.NaN())},
+		{obj, OpAdd, Number(1), Number(4)},
+		{obj, OpAdd, Str("!"), Str("3!")},
+		{Str("6"), OpSub, Str("2"), Number(4)},
+		{Number(7), OpRem, Number(-3), Number(1)},
+		{Number(-7), OpRem, Number(3), Number(-1)},
+		{Number(2), OpExp, Number(10), Number(1024)},
+		{Number(1), OpExp, Number(math.Inf(1)), Number(math.NaN())},
+		{Number(1), OpExp, Number(math.NaN()), Number(math.NaN())},
+		{Number(1), OpDiv, Number(math.Copysign(0, -1)), Number(math.Inf(-1))},
+		{Number(1), OpShl, Number(33), Number(2)},
+		{Number(-16), OpShr, Number(2), Number(-4)},
+		{Number(-1), OpUShr, Number(0), Number(4294967295)},
+		{Number(4294967297), OpBitOr, Number(0), Number(1)},
+		{Number(5), OpBitAnd, Number(3), Number(1)},
+		{Number(5), OpBitXor, Number(3), Number(6)},
+
+		{bigint(7), OpDiv, bigint(-2), bigint(-3)},
+		{bigint(-7), OpRem, bigint(2), bigint(-1)},
+		{bigint(2), OpExp, bigint(64), BigInt(new(big.Int).Lsh(big.NewInt(1), 64))},
+		{bigint(-9), OpShr, bigint(1), bigint(-5)},
+		{bigint(1), OpShl, bigint(-1), bigint(0)},
+		{bigint(-1), OpShr, BigInt(new(big.Int).Lsh(big.NewInt(1), 80)), bigint(-1)},
+		{bigint(-6), OpBitAnd, bigint(3), bigint(2)},
+		{bigint(1), OpAdd, Str("n"), Str("1n")},
+
+		{Number(1), OpLess, Number(2), Bool(true)},
+		{Number(1), OpLess, Number(math.NaN()), Bool(false)},
+		{Number(1), OpGreaterEqual, Number(math.NaN()), Bool(false)},
+		{Str("a"), OpLess, Str("b"), Bool(true)},
+		{Str("10"), OpLess, Str("9"), Bool(true)},
+		{Str("10"), OpLess, Number(9), Bool(false)},
+		{Null(), OpLessEqual, Number(0), Bool(true)},
+		{Undefined(), OpLessEqual, Number(0), Bool(false)},
+		{bigint(1), OpLess, Number(1.5), Bool(true)},
+		{Number(2.5), OpGreater, bigint(2), Bool(true)},
+		{bigint(2), OpLess, Str("x"), Bool(false)},
+		{bigint(2), OpGreaterEqual, Str("x"), Bool(false)},
+		{Str("1"), OpLess, bigint(2), Bool(true)},
+
+		{Number(1), OpEqual, Str("1"), Bool(true)},
+		{Number(1), OpNotEqual, Str("1"), Bool(false)},
+		{Number(1), OpStrictEqual, Str("1"), Bool(false)},
+		{Number(1), OpStrictNotEqual, Str("1"), Bool(true)},
+	}
+
+	for _, c := range tests {
+		v, err := BinaryOperator(c.op, c.x, c.y)
+		if err != nil {
+			t.Fatalf("%s %s %s: %v", c.x, c.op, c.y, err)
+		}
+		if !SameValue(v, c.out) && !(v.kind == KindBigInt && IsStrictlyEqual(v, c.out)) {
+			t.Fatalf("%s %s %s = %s, want %s", c.x, c.op, c.y, v, c.out)
+		}
+	}
+
+	errors := []struct {
+		x   Value
+		op  Operator
+		y   Value
+		typ ErrorType
+	}{
+		{bigint(1), OpAdd, Number(1), TypeError},
+		{bigint(1), OpUShr, bigint(1), TypeError},
+		{bigint(1), OpDiv, bigint(0), RangeError},
+		{bigint(1), OpRem, bigint(0), RangeError},
+		{bigint(2), OpExp, bigint(-1), RangeError},
+		{bigint(1), OpShl, BigInt(new(big.Int).Lsh(big.NewInt(1), 80)), RangeError},
+		{NewSymbol(nil).Value(), OpAdd, Str(""), TypeError},
+		{Str("x"), OpIn, Str("xyz"), TypeError},
+		{obj, OpInstanceof, obj, TypeError},
+	}
+
+	for _, c := range errors {
+		_, err := BinaryOperator(c.op, c.x, c.y)
+		if err == nil {
+			t.Fatalf("%s %s %s: expected an error", c.x, c.op, c.y)
+		}
+		test.AssertEqual(t, err.(*Exception).Type, c.typ)
+	}
+}
+
+func TestInstanceof(t *testing.T) {
+	proto := NewObject(nil)
+	ctor := NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		return Undefined(), nil
+	})
+	CreateDataProperty(ctor, StringKey("prototype"), proto.Value())
+
+	o := NewObject(NewObject(proto))
+	v, err := BinaryOperator(OpInstanceof, o.Value(), ctor.Value())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.AsBool(), true)
+	v, _ = BinaryOperator(OpInstanceof, NewObject(nil).Value(), ctor.Value())
+	test.AssertEqual(t, v.AsBool(), false)
+	v, _ = BinaryOperator(OpInstanceof, Number(1), ctor.Value())
+	test.AssertEqual(t, v.AsBool(), false)
+
+	// @@hasInstance overrides the prototype chain walk
+	CreateDataProperty(ctor, SymbolKey(SymbolHasInstance), NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		return Number(1), nil
+	}).Value())
+	v, _ = BinaryOperator(OpInstanceof, Number(1), ctor.Value())
+	test.AssertEqual(t, v.AsBool(), true)
+
+	CreateDataProperty(o, StringKey("k"), Null())
+	v, err = BinaryOperator(OpIn, Str("k"), o.Value())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.AsBool(), true)
+}
+
+func TestUnaryOperators(t *testing.T) {
+	test.AssertEqual(t, Typeof(Null()), "object")
+	test.AssertEqual(t, Typeof(bigint(1)), "bigint")
+	test.AssertEqual(t, Typeof(NewObject(nil).Value()), "object")
+	test.AssertEqual(t, Typeof(NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		return Undefined(), nil
+	}).Value()), "function")
+
+	v, _ := UnaryMinus(Number(0))
+	test.AssertEqual(t, math.Signbit(v.AsNumber()), true)
+	v, _ = UnaryMinus(bigint(5))
+	test.AssertEqual(t, v.AsBigInt().Int64(), int64(-5))
+	v, _ = BitwiseNot(Str("5"))
+	test.AssertEqual(t, v.AsNumber(), -6.0)
+	v, _ = BitwiseNot(bigint(5))
+	test.AssertEqual(t, v.AsBigInt().Int64(), int64(-6))
+	v, _ = Increment(Str("1"), 1)
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	v, _ = Increment(bigint(1), -1)
+	test.AssertEqual(t, v.AsBigInt().Int64(), int64(0))
+
+	test.AssertEqual(t, ToInt32(2147483648), int32(-2147483648))
+	test.AssertEqual(t, ToInt32(-1.9), int32(-1))
+	test.AssertEqual(t, ToUint32(-1), uint32(4294967295))
+	test.AssertEqual(t, ToUint32(math.NaN()), uint32(0))
+}
+
+func TestForInIterator(t *testing.T) {
+	proto := NewObject(nil)
+	CreateDataProperty(proto, StringKey("inherited"), Null())
+	CreateDataProperty(proto, StringKey("shadowed"), Null())
+	CreateDataProperty(proto, StringKey("hidden"), Null())
+	CreateDataProperty(proto, StringKey("deleted"), Null())
+
+	o := NewObject(proto)
+	CreateDataProperty(o, StringKey("b"), Null())
+	CreateDataProperty(o, StringKey("1"), Null())
+	CreateDataProperty(o, SymbolKey(NewSymbol(nil)), Null())
+	CreateDataProperty(o, StringKey("shadowed"), Null())
+	CreateDataProperty(o, StringKey("a"), Null())
+	o.DefineOwnProperty(StringKey("hidden"), DataDescriptor(Null(), true, false, true))
+
+	it := NewForInIterator(o)
+	var keys []string
+	for {
+		k, ok := it.Next()
+		if !ok {
+			break
+		}
+		keys = append(keys, k.String())
+		if len(keys) == 1 {
+			// Deleting a property not yet visited removes it from the iteration
+			proto.Delete(StringKey("deleted"))
+		}
+	}
+
+	expected := []string{"1", "b", "shadowed", "a", "inherited"}
+	test.AssertEqual(t, len(keys), len(expected))
+	for i, k := range expected {
+		test.AssertEqual(t, keys[i], k)
+	}
+}
@@ -0,0 +1,145 @@
+package runtime
+
+// PrivateName is the identity of a private class member such as #x.
+// Each evaluation of a class definition creates new private names, so
+// two classes declaring #x do not see each other's members.
+type PrivateName struct {
+	description string
+}
+
+// NewPrivateName creates a private name. description includes the #.
+func NewPrivateName(description string) *PrivateName {
+	return &PrivateName{description: description}
+}
+
+func (p *PrivateName) String() string {
+	return p.description
+}
+
+// PrivateEnvironment maps the private identifiers declared by a class
+// body to their private names.
+type PrivateEnvironment struct {
+	outer *PrivateEnvironment
+	names map[string]*PrivateName
+}
+
+// NewPrivateEnvironment creates an empty private environment.
+func NewPrivateEnvironment(outer *PrivateEnvironment) *PrivateEnvironment {
+	return &PrivateEnvironment{outer: outer, names: make(map[string]*PrivateName)}
+}
+
+// Declare creates the private name for identifier in e.
+func (e *PrivateEnvironment) Declare(identifier string) *PrivateName {
+	if p, ok := e.names[identifier]; ok {
+		return p // a getter and setter pair share their name
+	}
+	p := NewPrivateName(identifier)
+	e.names[identifier] = p
+	return p
+}
+
+// Resolve returns the private name identifier refers to in e or its
+// outer environments. The parser guarantees that one exists.
+func (e *PrivateEnvironment) Resolve(identifier string) *PrivateName {
+	for ; e != nil; e = e.outer {
+		if p, ok := e.names[identifier]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// PrivateElementKind is the kind of a private element.
+type PrivateElementKind uint8
+
+// Definition of private element kinds
+const (
+	PrivateField PrivateElementKind = iota
+	PrivateMethod
+	PrivateAccessor
+)
+
+// PrivateElement is a private field, method or accessor of an object.
+type PrivateElement struct {
+	Key   *PrivateName
+	Kind  PrivateElementKind
+	Value Value // fields and methods
+	Get   Value // accessors
+	Set   Value // accessors
+}
+
+// privateElementFind returns the private element p of o, or nil.
+func privateElementFind(o *Object, p *PrivateName) *PrivateElement {
+	for _, e := range o.private {
+		if e.Key == p {
+			return e
+		}
+	}
+	return nil
+}
+
+// PrivateFieldAdd adds the private field p to o. Adding a field twice
+// fails, which happens when a constructor returns an object that
+// already went through the same class's field initialization.
+func PrivateFieldAdd(o *Object, p *PrivateName, v Value) error {
+	if privateElementFind(o, p) != nil {
+		return throwTypeError("Cannot initialize %s twice on the same object", p)
+	}
+	o.private = append(o.private, &PrivateElement{Key: p, Kind: PrivateField, Value: v})
+	return nil
+}
+
+// PrivateMethodOrAccessorAdd installs a private method or accessor on
+// o, which brands o as an instance of the class declaring it.
+func PrivateMethodOrAccessorAdd(o *Object, m *PrivateElement) error {
+	if privateElementFind(o, m.Key) != nil {
+		return throwTypeError("Cannot initialize private methods of class twice on the same object")
+	}
+	e := *m
+	o.private = append(o.private, &e)
+	return nil
+}
+
+// PrivateGet evaluates o.#p.
+func PrivateGet(o *Object, p *PrivateName) (Value, error) {
+	e := privateElementFind(o, p)
+	if e == nil {
+		return Undefined(), throwTypeError("Cannot read private member %s from an object whose class did not declare it", p)
+	}
+	if e.Kind != PrivateAccessor {
+		return e.Value, nil
+	}
+	if e.Get.IsUndefined() {
+		return Undefined(), throwTypeError("'%s' was defined without a getter", p)
+	}
+	return Call(e.Get, o.Value())
+}
+
+// PrivateSet evaluates o.#p = v.
+func PrivateSet(o *Object, p *PrivateName, v Value) error {
+	e := privateElementFind(o, p)
+	if e == nil {
+		return throwTypeError("Cannot write private member %s to an object whose class did not declare it", p)
+	}
+	switch e.Kind {
+	case PrivateField:
+		e.Value = v
+		return nil
+	case PrivateMethod:
+		return throwTypeError("Private method %s is not writable", p)
+	}
+	if e.Set.IsUndefined() {
+		return throwTypeError("'%s' was defined without a setter", p)
+	}
+	_, err := Call(e.Set, o.Value(), v)
+	return err
+}
+
+// PrivateIn evaluates #p in v, the ergonomic brand check.
+func PrivateIn(p *PrivateName, v Value) (bool, error) {
+	o := v.AsObject()
+	if o == nil {
+		return false, throwTypeError("Cannot use 'in' operator to search for '%s' in %s", p, v)
+	}
+	return privateElementFind(o, p) != nil, nil
+}
@@ -0,0 +1,388 @@
+package runtime
+
+// PromiseState is the state of a promise.
+type PromiseState uint8
+
+// Definition of promise states
+const (
+	PromisePending PromiseState = iota
+	PromiseFulfilled
+	PromiseRejected
+)
+
+// promise holds the internal slots of a promise object.
+type promise struct {
+	state            PromiseState
+	result           Value
+	fulfillReactions []*promiseReaction
+	rejectReactions  []*promiseReaction
+	handled          bool
+}
+
+// promiseReaction is a handler waiting for a promise to settle. The
+// capability is nil for reactions whose result nobody observes, such
+// as those of await.
+type promiseReaction struct {
+	capability *PromiseCapability
+	reject     bool
+	handler    Value // callable, or undefined to pass the result through
+}
+
+// PromiseCapability is a promise together with the functions that
+// resolve or reject it.
+type PromiseCapability struct {
+	Promise *Object
+	Resolve Value
+	Reject  Value
+}
+
+// IsPromise reports whether v is a promise object.
+func IsPromise(v Value) bool {
+	o := v.AsObject()
+	return o != nil && o.promise() != nil
+}
+
+// PromiseResult returns the state of the promise p and its value or
+// rejection reason once settled.
+func PromiseResult(p *Object) (PromiseState, Value) {
+	return p.promise().state, p.promise().result
+}
+
+// NewPromise creates a pending %Promise% with its resolving functions.
+func (r *Realm) NewPromise() *PromiseCapability {
+	p := NewObject(r.PromisePrototype)
+	p.internal = &promise{}
+	resolve, reject := r.createResolvingFunctions(p)
+	return &PromiseCapability{Promise: p, Resolve: resolve.Value(), Reject: reject.Value()}
+}
+
+// createResolvingFunctions returns the resolve and reject functions of
+// p. Only the first call to either of them has an effect.
+func (r *Realm) createResolvingFunctions(p *Object) (*Object, *Object) {
+	alreadyResolved := false
+	resolve := r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		if alreadyResolved {
+			return Undefined(), nil
+		}
+		alreadyResolved = true
+		r.resolvePromise(p, argument(args, 0))
+		return Undefined(), nil
+	})
+	reject := r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		if alreadyResolved {
+			return Undefined(), nil
+		}
+		alreadyResolved = true
+		r.rejectPromise(p, argument(args, 0))
+		return Undefined(), nil
+	})
+	return resolve, reject
+}
+
+// resolvePromise resolves p with resolution: thenables are followed in
+// a job, other values fulfill p.
+func (r *Realm) resolvePromise(p *Object, resolution Value) {
+	o := resolution.AsObject()
+	if o == p {
+		r.rejectPromise(p, r.NewError(TypeError, "Chaining cycle detected for promise").Value())
+		return
+	}
+	if o == nil {
+		r.fulfillPromise(p, resolution)
+		return
+	}
+	then, err := Get(o, StringKey("then"))
+	if err != nil {
+		r.rejectPromise(p, r.ThrownValue(err))
+		return
+	}
+	if !IsCallable(then) {
+		r.fulfillPromise(p, resolution)
+		return
+	}
+
+	// NewPromiseResolveThenableJob
+	r.HostEnqueuePromiseJob(func() error {
+		resolve, reject := r.createResolvingFunctions(p)
+		if _, err := Call(then, resolution, resolve.Value(), reject.Value()); err != nil {
+			_, err = Call(reject.Value(), Undefined(), r.ThrownValue(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// fulfillPromise settles p with v and runs its fulfill reactions.
+func (r *Realm) fulfillPromise(p *Object, v Value) {
+	s := p.promise()
+	reactions := s.fulfillReactions
+	s.state, s.result = PromiseFulfilled, v
+	s.fulfillReactions, s.rejectReactions = nil, nil
+	r.triggerPromiseReactions(reactions, v)
+}
+
+// rejectPromise settles p with reason and runs its reject reactions.
+func (r *Realm) rejectPromise(p *Object, reason Value) {
+	s := p.promise()
+	reactions := s.rejectReactions
+	s.state, s.result = PromiseRejected, reason
+	s.fulfillReactions, s.rejectReactions = nil, nil
+	if !s.handled {
+		r.trackRejection(p, RejectionReject)
+	}
+	r.triggerPromiseReactions(reactions, reason)
+}
+
+func (r *Realm) triggerPromiseReactions(reactions []*promiseReaction, argument Value) {
+	for _, reaction := range reactions {
+		r.enqueuePromiseReactionJob(reaction, argument)
+	}
+}
+
+// enqueuePromiseReactionJob queues the call of a reaction handler,
+// whose result settles the derived promise of the reaction.
+func (r *Realm) enqueuePromiseReactionJob(reaction *promiseReaction, argument Value) {
+	r.HostEnqueuePromiseJob(func() error {
+		result := argument
+		var err error
+		if !reaction.handler.IsUndefined() {
+			result, err = Call(reaction.handler, Undefined(), argument)
+		} else if reaction.reject {
+			err = Throw(argument)
+		}
+		c := reaction.capability
+		if c == nil {
+			return err
+		}
+		if err != nil {
+			_, err = Call(c.Reject, Undefined(), r.ThrownValue(err))
+		} else {
+			_, err = Call(c.Resolve, Undefined(), result)
+		}
+		return err
+	})
+}
+
+// NewPromiseCapability creates a promise with the constructor c, which
+// may be a subclass of Promise, and returns its resolving functions.
+func (r *Realm) NewPromiseCapability(c Value) (*PromiseCapability, error) {
+	if !IsConstructor(c) {
+		return nil, throwTypeError("%s is not a constructor", c)
+	}
+	capability := &PromiseCapability{Resolve: Undefined(), Reject: Undefined()}
+	executor := r.NewBuiltinFunction("", 2, func(this Value, args []Value) (Value, error) {
+		if !capability.Resolve.IsUndefined() || !capability.Reject.IsUndefined() {
+			return Undefined(), throwTypeError("Promise executor has already been invoked with non-undefined arguments")
+		}
+		capability.Resolve, capability.Reject = argument(args, 0), argument(args, 1)
+		return Undefined(), nil
+	})
+	p, err := Construct(c.AsObject(), []Value{executor.Value()}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !IsCallable(capability.Resolve) || !IsCallable(capability.Reject) {
+		return nil, throwTypeError("Promise resolve or reject function is not callable")
+	}
+	capability.Promise = p.AsObject()
+	return capability, nil
+}
+
+// PerformPromiseThen adds fulfill and reject handlers to the promise
+// p. The handlers' result settles the promise of capability, which
+// may be nil.
+func (r *Realm) PerformPromiseThen(p *Object, onFulfilled, onRejected Value, capability *PromiseCapability) {
+	if !IsCallable(onFulfilled) {
+		onFulfilled = Undefined()
+	}
+	if !IsCallable(onRejected) {
+		onRejected = Undefined()
+	}
+	fulfill := &promiseReaction{capability: capability, handler: onFulfilled}
+	reject := &promiseReaction{capability: capability, reject: true, handler: onRejected}
+
+	s := p.promise()
+	switch s.state {
+	case PromisePending:
+		s.fulfillReactions = append(s.fulfillReactions, fulfill)
+		s.rejectReactions = append(s.rejectReactions, reject)
+	case PromiseFulfilled:
+		r.enqueuePromiseReactionJob(fulfill, s.result)
+	case PromiseRejected:
+		if !s.handled {
+			r.trackRejection(p, RejectionHandle)
+		}
+		r.enqueuePromiseReactionJob(reject, s.result)
+	}
+	s.handled = true
+}
+
+// PromiseResolve returns x if it is a promise created by c, or else a
+// new promise of c resolved with x.
+func (r *Realm) PromiseResolve(c *Object, x Value) (*Object, error) {
+	if IsPromise(x) {
+		ctor, err := Get(x.AsObject(), StringKey("constructor"))
+		if err != nil {
+			return nil, err
+		}
+		if ctor.AsObject() == c {
+			return x.AsObject(), nil
+		}
+	}
+	capability, err := r.NewPromiseCapability(c.Value())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Call(capability.Resolve, Undefined(), x); err != nil {
+		return nil, err
+	}
+	return capability.Promise, nil
+}
+
+// SpeciesConstructor returns the constructor that methods of o use to
+// create derived objects: constructor[@@species], or fallback.
+func SpeciesConstructor(o *Object, fallback *Object) (*Object, error) {
+	c, err := Get(o, StringKey("constructor"))
+	if err != nil || c.IsUndefined() {
+		return fallback, err
+	}
+	if !c.IsObject() {
+		return nil, throwTypeError("The .constructor property is not an object")
+	}
+	s, err := Get(c.AsObject(), SymbolKey(SymbolSpecies))
+	if err != nil || s.IsNullish() {
+		return fallback, err
+	}
+	if !IsConstructor(s) {
+		return nil, throwTypeError("object.constructor[Symbol.species] is not a constructor")
+	}
+	return s.AsObject(), nil
+}
+
+// invoke calls the method name of v.
+func (r *Realm) invoke(v Value, name string, args ...Value) (Value, error) {
+	f, err := r.GetV(v, StringKey(name))
+	if err != nil {
+		return Undefined(), err
+	}
+	return Call(f, v, args...)
+}
+
+// initPromise creates the Promise constructor and %Promise.prototype%.
+func (r *Realm) initPromise() {
+	proto := NewObject(r.ObjectPrototype)
+	r.PromisePrototype = proto
+	ctor := r.NewBuiltinConstructor("Promise", 1, func(args []Value, newTarget *Object) (Value, error) {
+		if newTarget == nil {
+			return Undefined(), throwTypeError("Promise constructor cannot be invoked without 'new'")
+		}
+		executor := argument(args, 0)
+		if !IsCallable(executor) {
+			return Undefined(), throwTypeError("Promise resolver %s is not a function", executor)
+		}
+		p, err := r.OrdinaryCreateFromConstructor(newTarget, func(r *Realm) *Object {
+			return r.PromisePrototype
+		})
+		if err != nil {
+			return Undefined(), err
+		}
+		p.internal = &promise{}
+		resolve, reject := r.createResolvingFunctions(p)
+		if _, err := Call(executor, Undefined(), resolve.Value(), reject.Value()); err != nil {
+			if _, err := Call(reject.Value(), Undefined(), r.ThrownValue(err)); err != nil {
+				return Undefined(), err
+			}
+		}
+		return p.Value(), nil
+	})
+	r.PromiseConstructor = ctor
+	ctor.DefineOwnProperty(StringKey("prototype"), DataDescriptor(proto.Value(), false, false, false))
+	proto.DefineOwnProperty(StringKey("constructor"), DataDescriptor(ctor.Value(), true, false, true))
+	proto.DefineOwnProperty(SymbolKey(SymbolToStringTag), DataDescriptor(Str("Promise"), false, false, true))
+	species := r.NewBuiltinFunction("get [Symbol.species]", 0, func(this Value, args []Value) (Value, error) {
+		return this, nil
+	})
+	ctor.DefineOwnProperty(SymbolKey(SymbolSpecies), AccessorDescriptor(species.Value(), Undefined(), false, true))
+
+	r.defineMethod(ctor, "resolve", 1, func(this Value, args []Value) (Value, error) {
+		c := this.AsObject()
+		if c == nil {
+			return Undefined(), throwTypeError("PromiseResolve called on non-object")
+		}
+		p, err := r.PromiseResolve(c, argument(args, 0))
+		if err != nil {
+			return Undefined(), err
+		}
+		return p.Value(), nil
+	})
+
+	r.defineMethod(ctor, "reject", 1, func(this Value, args []Value) (Value, error) {
+		capability, err := r.NewPromiseCapability(this)
+		if err != nil {
+			return Undefined(), err
+		}
+		if _, err := Call(capability.Reject, Undefined(), argument(args, 0)); err != nil {
+			return Undefined(), err
+		}
+		return capability.Promise.Value(), nil
+	})
+
+	r.defineMethod(proto, "then", 2, func(this Value, args []Value) (Value, error) {
+		if !IsPromise(this) {
+			return Undefined(), throwTypeError("Method Promise.prototype.then called on incompatible receiver %s", this)
+		}
+		p := this.AsObject()
+		c, err := SpeciesConstructor(p, r.PromiseConstructor)
+		if err != nil {
+			return Undefined(), err
+		}
+		capability, err := r.NewPromiseCapability(c.Value())
+		if err != nil {
+			return Undefined(), err
+		}
+		r.PerformPromiseThen(p, argument(args, 0), argument(args, 1), capability)
+		return capability.Promise.Value(), nil
+	})
+
+	r.defineMethod(proto, "catch", 1, func(this Value, args []Value) (Value, error) {
+		return r.invoke(this, "then", Undefined(), argument(args, 0))
+	})
+
+	r.defineMethod(proto, "finally", 1, func(this Value, args []Value) (Value, error) {
+		p := this.AsObject()
+		if p == nil {
+			return Undefined(), throwTypeError("Method Promise.prototype.finally called on incompatible receiver %s", this)
+		}
+		c, err := SpeciesConstructor(p, r.PromiseConstructor)
+		if err != nil {
+			return Undefined(), err
+		}
+		onFinally := argument(args, 0)
+		if !IsCallable(onFinally) {
+			return r.invoke(this, "then", onFinally, onFinally)
+		}
+
+		// settle returns a handler that runs onFinally, waits for its
+		// result and then passes the original outcome through
+		settle := func(outcome func(Value) (Value, error)) Value {
+			return r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+				v := argument(args, 0)
+				result, err := Call(onFinally, Undefined())
+				if err != nil {
+					return Undefined(), err
+				}
+				promise, err := r.PromiseResolve(c, result)
+				if err != nil {
+					return Undefined(), err
+				}
+				thunk := r.NewBuiltinFunction("", 0, func(Value, []Value) (Value, error) {
+					return outcome(v)
+				})
+				return r.invoke(promise.Value(), "then", thunk.Value())
+			}).Value()
+		}
+		thenFinally := settle(func(v Value) (Value, error) { return v, nil })
+		catchFinally := settle(func(v Value) (Value, error) { return Undefined(), Throw(v) })
+		return r.invoke(this, "then", thenFinally, catchFinally)
+	})
+}
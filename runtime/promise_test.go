@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+// logger returns a function appending prefix and its argument to *log.
+func logger(r *Realm, log *[]string, prefix string) Value {
+	return r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		*log = append(*log, prefix+argument(args, 0).String())
+		return argument(args, 0), nil
+	}).Value()
+}
+
+func TestPromise(t *testing.T) {
+	r := NewRealm()
+	var log []string
+
+	// Handlers run as jobs, in the order they were queued
+	a := r.NewPromise()
+	b := r.NewPromise()
+	r.invoke(a.Promise.Value(), "then", logger(r, &log, "a "))
+	r.invoke(b.Promise.Value(), "catch", logger(r, &log, "b "))
+	Call(b.Reject, Undefined(), Str("2"))
+	Call(a.Resolve, Undefined(), Str("1"))
+	Call(a.Resolve, Undefined(), Str("ignored"))
+	test.AssertEqual(t, len(log), 0)
+	test.AssertEqual(t, r.PendingJobs(), 2)
+	test.AssertEqual(t, r.RunJobs(), nil)
+	test.AssertEqual(t, strings.Join(log, ", "), "b 2, a 1")
+	state, v := PromiseResult(a.Promise)
+	test.AssertEqual(t, state, PromiseFulfilled)
+	test.AssertEqual(t, v.String(), "1")
+
+	// Chained handlers get the result of the previous one
+	log = nil
+	p, _ := r.invoke(a.Promise.Value(), "then", r.NewBuiltinFunction("", 1, func(this Value, args []Value) (Value, error) {
+		return Undefined(), Throw(Str("boom"))
+	}).Value())
+	p, _ = r.invoke(p, "then", logger(r, &log, "fulfilled "), logger(r, &log, "rejected "))
+	p, _ = r.invoke(p, "finally", logger(r, &log, "finally "))
+	r.invoke(p, "then", logger(r, &log, "after "))
+	r.RunJobs()
+	test.AssertEqual(t, strings.Join(log, ", "), "rejected boom, finally undefined, after boom")
+
+	// Thenables are adopted in a job of their own
+	log = nil
+	thenable := NewObject(r.ObjectPrototype)
+	r.defineMethod(thenable, "then", 2, func(this Value, args []Value) (Value, error) {
+		log = append(log, "then")
+		return Call(argument(args, 0), Undefined(), Str("adopted"))
+	})
+	c := r.NewPromise()
+	r.invoke(c.Promise.Value(), "then", logger(r, &log, ""))
+	Call(c.Resolve, Undefined(), thenable.Value())
+	test.AssertEqual(t, len(log), 0)
+	r.RunJobs()
+	test.AssertEqual(t, strings.Join(log, ", "), "then, adopted")
+
+	// A promise cannot resolve itself
+	d := r.NewPromise()
+	Call(d.Resolve, Undefined(), d.Promise.Value())
+	state, v = PromiseResult(d.Promise)
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, v.AsObject().GetPrototypeOf(), r.nativeErrorPrototypes[TypeError])
+
+	// The executor throwing rejects the promise
+	ctor := r.PromiseConstructor
+	e, err := Construct(ctor, []Value{r.NewBuiltinFunction("", 2, func(this Value, args []Value) (Value, error) {
+		return Undefined(), Throw(Str("executor"))
+	}).Value()}, nil)
+	test.AssertEqual(t, err, nil)
+	state, v = PromiseResult(e.AsObject())
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, v.String(), "executor")
+	_, err = Call(ctor.Value(), Undefined(), logger(r, &log, ""))
+	test.AssertEqual(t, errorType(err), TypeError)
+
+	// Errors of host functions reject with an Error object
+	e, err = Construct(ctor, []Value{r.NewBuiltinFunction("", 2, func(this Value, args []Value) (Value, error) {
+		return Undefined(), errors.New("host failure")
+	}).Value()}, nil)
+	test.AssertEqual(t, err, nil)
+	state, v = PromiseResult(e.AsObject())
+	test.AssertEqual(t, state, PromiseRejected)
+	test.AssertEqual(t, v.AsObject().GetPrototypeOf(), r.ErrorPrototype)
+	message, _ := Get(v.AsObject(), StringKey("message"))
+	test.AssertEqual(t, message.String(), "host failure")
+
+	// Promise.resolve returns promises of the same constructor as is
+	resolved, _ := r.invoke(ctor.Value(), "resolve", e)
+	test.AssertEqual(t, resolved.AsObject(), e.AsObject())
+	resolved, _ = r.invoke(ctor.Value(), "resolve", Number(1))
+	test.AssertEqual(t, IsPromise(resolved), true)
+}
+
+func TestRejectionTracker(t *testing.T) {
+	r := NewRealm()
+	ops := ""
+	r.RejectionTracker = func(p *Object, op RejectionOperation) {
+		ops += [...]string{RejectionReject: "reject ", RejectionHandle: "handle "}[op]
+	}
+
+	rejected, _ := r.invoke(r.PromiseConstructor.Value(), "reject", Str("unhandled"))
+	test.AssertEqual(t, ops, "reject ")
+	r.RunJobs()
+
+	// Handling it later is reported, and its derived promise too is rejected
+	derived, _ := r.invoke(rejected, "then", Undefined())
+	test.AssertEqual(t, ops, "reject handle ")
+	r.RunJobs()
+	test.AssertEqual(t, ops, "reject handle reject ")
+	state, _ := PromiseResult(derived.AsObject())
+	test.AssertEqual(t, state, PromiseRejected)
+
+	// Rejections with handlers are not reported
+	ops = ""
+	p := r.NewPromise()
+	r.invoke(p.Promise.Value(), "catch", r.NewBuiltinFunction("", 1, func(Value, []Value) (Value, error) {
+		return Undefined(), nil
+	}).Value())
+	Call(p.Reject, Undefined(), Str("handled"))
+	r.RunJobs()
+	test.AssertEqual(t, ops, "")
+}
@@ -0,0 +1,166 @@
+package runtime
+
+import "math"
+
+// Realm is a global environment together with the intrinsic objects
+// that code running in it uses, such as %Object.prototype%.
+type Realm struct {
+	ObjectPrototype   *Object
+	FunctionPrototype *Object
+	BooleanPrototype  *Object
+	NumberPrototype   *Object
+	StringPrototype   *Object
+	SymbolPrototype   *Object
+	BigIntPrototype   *Object
+	ErrorPrototype    *Object
+
+	IteratorPrototype          *Object
+	ArrayIteratorPrototype     *Object
+	StringIteratorPrototype    *Object
+	ArrayValues                *Object // %Array.prototype.values%
+	GeneratorFunctionPrototype *Object
+	GeneratorPrototype         *Object
+
+	PromisePrototype                *Object
+	PromiseConstructor              *Object // %Promise%
+	AsyncFunctionPrototype          *Object
+	AsyncIteratorPrototype          *Object
+	AsyncFromSyncIteratorPrototype  *Object
+	AsyncGeneratorFunctionPrototype *Object
+	AsyncGeneratorPrototype         *Object
+
+	ThrowTypeError *Object // %ThrowTypeError%, the poison pill of strict arguments.callee
+	GlobalObject   *Object
+	GlobalEnv      *GlobalEnvironment
+
+	// RejectionTracker, if set, is notified of promises rejected
+	// without handlers and of handlers added to them later.
+	RejectionTracker RejectionTracker
+
+	jobs                  []Job // the promise job queue, drained by RunJobs
+	nativeErrorPrototypes [SyntaxError + 1]*Object
+}
+
+// NewRealm creates a realm with its intrinsics and global environment.
+func NewRealm() *Realm {
+	r := &Realm{ObjectPrototype: NewObject(nil)}
+	r.FunctionPrototype = NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		return Undefined(), nil
+	})
+	r.FunctionPrototype.SetPrototypeOf(r.ObjectPrototype)
+	SetFunctionLength(r.FunctionPrototype, 0)
+	SetFunctionName(r.FunctionPrototype, StringKey(""), "")
+	r.initFunctionPrototype()
+
+	for _, p := range []**Object{&r.BooleanPrototype, &r.NumberPrototype, &r.StringPrototype, &r.SymbolPrototype, &r.BigIntPrototype} {
+		*p = NewObject(r.ObjectPrototype)
+	}
+
+	r.ThrowTypeError = r.NewBuiltinFunction("", 0, func(this Value, args []Value) (Value, error) {
+		return Undefined(), throwTypeError("'caller', 'callee', and 'arguments' properties may not be accessed on strict mode functions or the arguments objects for calls to them")
+	})
+	r.ThrowTypeError.PreventExtensions()
+	for _, name := range []string{"length", "name"} {
+		r.ThrowTypeError.DefineOwnProperty(StringKey(name), PropertyDescriptor{Configurable: false, Fields: HasConfigurable})
+	}
+
+	r.initIterators()
+	r.initGenerators()
+
+	g := NewObject(r.ObjectPrototype)
+	r.GlobalObject = g
+	r.GlobalEnv = NewGlobalEnvironment(g, g)
+	g.DefineOwnProperty(StringKey("globalThis"), DataDescriptor(g.Value(), true, false, true))
+	g.DefineOwnProperty(StringKey("undefined"), DataDescriptor(Undefined(), false, false, false))
+	g.DefineOwnProperty(StringKey("NaN"), DataDescriptor(Number(math.NaN()), false, false, false))
+	g.DefineOwnProperty(StringKey("Infinity"), DataDescriptor(Number(math.Inf(1)), false, false, false))
+	r.initError()
+	r.initPromise()
+	r.initAsync()
+	g.DefineOwnProperty(StringKey("Promise"), DataDescriptor(r.PromiseConstructor.Value(), true, false, true))
+	return r
+}
+
+// NewBuiltinFunction creates a function object backed by fn with the
+// given name and length, whose prototype is %Function.prototype%.
+func (r *Realm) NewBuiltinFunction(name string, length int, fn NativeFunction) *Object {
+	f := NewNativeFunction(fn)
+	f.internal = r // [[Realm]]
+	f.SetPrototypeOf(r.FunctionPrototype)
+	SetFunctionLength(f, float64(length))
+	SetFunctionName(f, StringKey(name), "")
+	return f
+}
+
+// NewBuiltinConstructor creates a built-in constructor backed by fn.
+// When it is called rather than constructed, fn gets a nil newTarget.
+func (r *Realm) NewBuiltinConstructor(name string, length int, fn ConstructFunction) *Object {
+	f := r.NewBuiltinFunction(name, length, func(this Value, args []Value) (Value, error) {
+		return fn(args, nil)
+	})
+	f.construct = fn
+	return f
+}
+
+// defineMethod defines a built-in method of o, which is writable and
+// configurable but not enumerable.
+func (r *Realm) defineMethod(o *Object, name string, length int, fn NativeFunction) {
+	f := r.NewBuiltinFunction(name, length, fn)
+	o.DefineOwnProperty(StringKey(name), DataDescriptor(f.Value(), true, false, true))
+}
+
+// ToObject converts v to an object, wrapping primitives in Boolean,
+// Number, String, Symbol and BigInt objects.
+func (r *Realm) ToObject(v Value) (*Object, error) {
+	if o := v.AsObject(); o != nil {
+		return o, nil
+	}
+	proto, err := r.primitivePrototype(v)
+	if err != nil {
+		return nil, err
+	}
+	var o *Object
+	if v.kind == KindString {
+		o = newExoticObject(stringObject{}, proto)
+	} else {
+		o = NewObject(proto)
+	}
+	o.internal = v
+	return o, nil
+}
+
+// primitivePrototype returns the prototype of the wrapper objects of
+// the primitive v.
+func (r *Realm) primitivePrototype(v Value) (*Object, error) {
+	switch v.kind {
+	case KindBoolean:
+		return r.BooleanPrototype, nil
+	case KindNumber:
+		return r.NumberPrototype, nil
+	case KindString:
+		return r.StringPrototype, nil
+	case KindSymbol:
+		return r.SymbolPrototype, nil
+	case KindBigInt:
+		return r.BigIntPrototype, nil
+	}
+	return nil, throwTypeError("Cannot convert undefined or null to object")
+}
+
+// GetV returns the property key of v. Properties of primitives are
+// read as from their wrapper objects, without creating one.
+func (r *Realm) GetV(v Value, key PropertyKey) (Value, error) {
+	if o := v.AsObject(); o != nil {
+		return o.Get(key, v)
+	}
+	if s := v.AsString(); s != nil {
+		if desc, ok := stringGetOwnProperty(s, key); ok {
+			return desc.Value, nil
+		}
+	}
+	proto, err := r.primitivePrototype(v)
+	if err != nil {
+		return Undefined(), err
+	}
+	return proto.Get(key, v)
+}
@@ -0,0 +1,199 @@
+package runtime
+
+// maxShapeProperties is the number of properties above which an object
+// leaves the transition tree and gets a dictionary shape of its own.
+const maxShapeProperties = 64
+
+// shapeIndexThreshold is the number of properties above which a shape
+// builds a key index instead of scanning its keys.
+const shapeIndexThreshold = 8
+
+// shape describes the layout of the own properties of an object: their
+// keys, attributes and slot indices. Objects with the same prototype
+// that get the same properties in the same order share a shape, so
+// property values live in a plain slot array and a shape pointer
+// comparison is enough to validate an inline cache.
+//
+// Shapes form a transition tree rooted at the root shape of a
+// prototype. Dictionary shapes are owned by a single object, are
+// modified in place and are never cached.
+type shape struct {
+	parent      *shape
+	keys        []PropertyKey // keys in slot order
+	attrs       []attributes  // attributes in slot order
+	index       map[PropertyKey]int
+	transitions map[transition]*shape
+	dictionary  bool
+}
+
+// transition identifies the child of a shape that adds key with attrs.
+type transition struct {
+	key   PropertyKey
+	attrs attributes
+}
+
+// rootShapeFor returns the empty shape of objects whose prototype is
+// proto. Objects without a prototype do not share shapes.
+func rootShapeFor(proto *Object) *shape {
+	if proto == nil {
+		return &shape{}
+	}
+	if proto.rootShape == nil {
+		proto.rootShape = &shape{}
+	}
+	return proto.rootShape
+}
+
+// lookup returns the slot index of key, or -1.
+func (s *shape) lookup(key PropertyKey) int {
+	if s.index != nil {
+		if i, ok := s.index[key]; ok {
+			return i
+		}
+		return -1
+	}
+	if len(s.keys) > shapeIndexThreshold {
+		s.buildIndex()
+		return s.lookup(key)
+	}
+	for i, k := range s.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *shape) buildIndex() {
+	s.index = make(map[PropertyKey]int, len(s.keys))
+	for i, k := range s.keys {
+		s.index[k] = i
+	}
+}
+
+// withProperty returns the shape obtained by adding key with attrs to
+// s, following or creating a transition. The new key gets the next slot.
+func (s *shape) withProperty(key PropertyKey, attrs attributes) *shape {
+	if s.dictionary {
+		s.keys = append(s.keys, key)
+		s.attrs = append(s.attrs, attrs)
+		s.index[key] = len(s.keys) - 1
+		return s
+	}
+
+	t := transition{key, attrs}
+	if child, ok := s.transitions[t]; ok {
+		return child
+	}
+	n := len(s.keys)
+	child := &shape{
+		parent: s,
+		keys:   append(s.keys[:n:n], key),
+		attrs:  append(s.attrs[:n:n], attrs),
+	}
+	if s.transitions == nil {
+		s.transitions = make(map[transition]*shape)
+	}
+	s.transitions[t] = child
+	return child
+}
+
+// root returns the root of the transition tree s belongs to.
+func (s *shape) root() *shape {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// rebuild returns the shape with the keys of s in the same order, but
+// starting from root and with the attributes of slot i replaced.
+func (s *shape) rebuild(root *shape, i int, attrs attributes) *shape {
+	r := root
+	for j, k := range s.keys {
+		a := s.attrs[j]
+		if j == i {
+			a = attrs
+		}
+		r = r.withProperty(k, a)
+	}
+	return r
+}
+
+// toDictionary returns an unshared copy of s that can be modified in place.
+func (s *shape) toDictionary() *shape {
+	d := &shape{
+		keys:       append([]PropertyKey(nil), s.keys...),
+		attrs:      append([]attributes(nil), s.attrs...),
+		dictionary: true,
+	}
+	d.buildIndex()
+	return d
+}
+
+// storeProperty stores the complete descriptor d under key, adding the
+// property if exists is false.
+func (o *Object) storeProperty(key PropertyKey, d PropertyDescriptor, exists bool) {
+	attrs := attributesOf(d)
+	if !exists {
+		o.shape = o.shape.withProperty(key, attrs)
+		o.slots = append(o.slots, slotValue(d))
+		if !o.shape.dictionary && len(o.shape.keys) > maxShapeProperties {
+			o.shape = o.shape.toDictionary()
+		}
+		return
+	}
+
+	i := o.shape.lookup(key)
+	o.slots[i] = slotValue(d)
+	if o.shape.attrs[i] == attrs {
+		return
+	}
+	if o.shape.dictionary {
+		o.shape.attrs[i] = attrs
+		return
+	}
+	o.shape = o.shape.rebuild(o.shape.root(), i, attrs)
+}
+
+// removeProperty removes the own property key. Removing the most
+// recently added property goes back to the parent shape; removing any
+// other property moves the object to a dictionary shape.
+func (o *Object) removeProperty(key PropertyKey) {
+	i := o.shape.lookup(key)
+	if i < 0 {
+		return
+	}
+
+	last := len(o.slots) - 1
+	if i == last && !o.shape.dictionary && o.shape.parent != nil {
+		o.shape = o.shape.parent
+		o.slots[last] = Value{}
+		o.slots = o.slots[:last]
+		return
+	}
+
+	if !o.shape.dictionary {
+		o.shape = o.shape.toDictionary()
+	}
+	s := o.shape
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.attrs = append(s.attrs[:i], s.attrs[i+1:]...)
+	s.buildIndex()
+	copy(o.slots[i:], o.slots[i+1:])
+	o.slots[last] = Value{}
+	o.slots = o.slots[:last]
+}
+
+// changePrototype moves o to the transition tree of its new prototype.
+func (o *Object) changePrototype(proto *Object) {
+	o.proto = proto
+	if o.shape.dictionary {
+		return
+	}
+	root := rootShapeFor(proto)
+	if _, ok := o.methods.(ordinary); !ok {
+		root = &shape{}
+	}
+	o.shape = o.shape.rebuild(root, -1, 0)
+}
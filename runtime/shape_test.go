@@ -0,0 +1,288 @@
+package runtime
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func TestShapeSharing(t *testing.T) {
+	proto := NewObject(nil)
+	a, b := NewObject(proto), NewObject(proto)
+	for _, o := range []*Object{a, b} {
+		CreateDataProperty(o, StringKey("x"), Number(1))
+		CreateDataProperty(o, StringKey("y"), Number(2))
+	}
+	test.AssertEqual(t, a.shape, b.shape)
+
+	// Different insertion order, attributes or prototype give different shapes
+	c := NewObject(proto)
+	CreateDataProperty(c, StringKey("y"), Number(2))
+	CreateDataProperty(c, StringKey("x"), Number(1))
+	test.AssertEqual(t, a.shape == c.shape, false)
+
+	d := NewObject(NewObject(nil))
+	CreateDataProperty(d, StringKey("x"), Number(1))
+	CreateDataProperty(d, StringKey("y"), Number(2))
+	test.AssertEqual(t, a.shape == d.shape, false)
+
+	// Changing attributes moves to a shared sibling shape
+	SetIntegrityLevel(a, Frozen)
+	SetIntegrityLevel(b, Frozen)
+	test.AssertEqual(t, a.shape, b.shape)
+	test.AssertEqual(t, a.shape.dictionary, false)
+	v, _ := Get(a, StringKey("y"))
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+
+	// Changing the prototype moves to the new prototype's tree
+	test.AssertEqual(t, d.SetPrototypeOf(proto), true)
+	e := NewObject(proto)
+	CreateDataProperty(e, StringKey("x"), Number(1))
+	CreateDataProperty(e, StringKey("y"), Number(2))
+	test.AssertEqual(t, d.shape, e.shape)
+}
+
+func TestShapeDeletion(t *testing.T) {
+	proto := NewObject(nil)
+	o := NewObject(proto)
+	for _, k := range []string{"a", "b", "c"} {
+		CreateDataProperty(o, StringKey(k), Str(k))
+	}
+	abc := o.shape
+
+	// Deleting the last property goes back to the parent shape
+	o.Delete(StringKey("c"))
+	test.AssertEqual(t, o.shape, abc.parent)
+	CreateDataProperty(o, StringKey("c"), Str("c"))
+	test.AssertEqual(t, o.shape, abc)
+
+	// Deleting another property makes a dictionary
+	o.Delete(StringKey("a"))
+	test.AssertEqual(t, o.shape.dictionary, true)
+	test.AssertEqual(t, HasOwnProperty(o, StringKey("a")), false)
+	v, _ := Get(o, StringKey("c"))
+	test.AssertEqual(t, v.String(), "c")
+	CreateDataProperty(o, StringKey("a"), Str("a2"))
+	keys := o.OwnPropertyKeys()
+	test.AssertEqual(t, keys[0], StringKey("b"))
+	test.AssertEqual(t, keys[2], StringKey("a"))
+	v, _ = Get(o, StringKey("a"))
+	test.AssertEqual(t, v.String(), "a2")
+
+	// The shared shape is untouched
+	test.AssertEqual(t, abc.lookup(StringKey("a")), 0)
+	test.AssertEqual(t, len(abc.keys), 3)
+}
+
+func TestShapeDictionaryThreshold(t *testing.T) {
+	o := NewObject(nil)
+	for i := 0; i <= maxShapeProperties; i++ {
+		CreateDataProperty(o, StringKey("p"+strconv.Itoa(i)), Number(float64(i)))
+	}
+	test.AssertEqual(t, o.shape.dictionary, true)
+	for i := 0; i <= maxShapeProperties; i++ {
+		v, _ := Get(o, StringKey("p"+strconv.Itoa(i)))
+		test.AssertEqual(t, v.AsNumber(), float64(i))
+	}
+}
+
+func TestInlineCache(t *testing.T) {
+	proto := NewObject(nil)
+	CreateDataProperty(proto, StringKey("method"), Str("inherited"))
+	newPoint := func(keys ...string) *Object {
+		o := NewObject(proto)
+		for i, k := range keys {
+			CreateDataProperty(o, StringKey(k), Number(float64(i)))
+		}
+		return o
+	}
+
+	var c InlineCache
+	x := StringKey("x")
+
+	// Monomorphic
+	p := newPoint("x", "y")
+	for i := 0; i < 3; i++ {
+		v, err := c.Get(p, x)
+		test.AssertEqual(t, err, nil)
+		test.AssertEqual(t, v.AsNumber(), 0.0)
+	}
+	test.AssertEqual(t, c.n, 1)
+	v, _ := c.Get(newPoint("x", "y"), x)
+	test.AssertEqual(t, v.AsNumber(), 0.0)
+	test.AssertEqual(t, c.n, 1)
+
+	// Polymorphic
+	v, _ = c.Get(newPoint("y", "x"), x)
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+	test.AssertEqual(t, c.n, 2)
+	v, _ = c.Get(newPoint("x", "y"), x)
+	test.AssertEqual(t, v.AsNumber(), 0.0)
+	test.AssertEqual(t, c.n, 2)
+
+	// Megamorphic
+	for _, keys := range [][]string{{"a", "x"}, {"a", "b", "x"}, {"a", "b", "c", "x"}} {
+		v, _ = c.Get(newPoint(keys...), x)
+		test.AssertEqual(t, v.AsNumber(), float64(len(keys)-1))
+	}
+	test.AssertEqual(t, c.megamorphic, true)
+	v, _ = c.Get(p, x)
+	test.AssertEqual(t, v.AsNumber(), 0.0)
+
+	// Inherited properties are invalidated when the holder changes
+	var m InlineCache
+	method := StringKey("method")
+	v, _ = m.Get(p, method)
+	test.AssertEqual(t, v.String(), "inherited")
+	test.AssertEqual(t, m.n, 1)
+	CreateDataProperty(proto, method, Str("replaced"))
+	v, _ = m.Get(p, method)
+	test.AssertEqual(t, v.String(), "replaced")
+	CreateDataProperty(p, method, Str("own"))
+	v, _ = m.Get(p, method)
+	test.AssertEqual(t, v.String(), "own")
+
+	// Writes to read-only properties are not cached
+	var s InlineCache
+	q := newPoint("x")
+	test.AssertEqual(t, s.Set(q, x, Number(5), true), nil)
+	test.AssertEqual(t, s.Set(q, x, Number(6), true), nil)
+	v, _ = Get(q, x)
+	test.AssertEqual(t, v.AsNumber(), 6.0)
+	SetIntegrityLevel(q, Frozen)
+	test.AssertEqual(t, s.Set(q, x, Number(7), true).(*Exception).Type, TypeError)
+	v, _ = Get(q, x)
+	test.AssertEqual(t, v.AsNumber(), 6.0)
+
+	// Assignments adding a property to objects of one shape stay monomorphic
+	var a InlineCache
+	for i := 0; i < 10; i++ {
+		test.AssertEqual(t, a.Set(newPoint(), x, Number(float64(i)), true), nil)
+	}
+	test.AssertEqual(t, a.n, 1)
+	test.AssertEqual(t, a.megamorphic, false)
+
+	// and so do repeated writes to read-only properties
+	var ro InlineCache
+	for i := 0; i < 10; i++ {
+		ro.Set(q, x, Number(8), false)
+	}
+	test.AssertEqual(t, ro.n, 1)
+
+	// Accessors are never cached
+	var g InlineCache
+	calls := 0
+	getter := NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		calls++
+		return Number(float64(calls)), nil
+	}).Value()
+	r := newPoint()
+	r.DefineOwnProperty(x, AccessorDescriptor(getter, Undefined(), true, true))
+	g.Get(r, x)
+	v, _ = g.Get(r, x)
+	test.AssertEqual(t, v.AsNumber(), 2.0)
+	test.AssertEqual(t, g.n, 0)
+}
+
+// newBenchmarkObjects creates n objects with the same shape, as
+// instances of a constructor would have.
+func newBenchmarkObjects(n int) (*Object, []*Object) {
+	proto := NewObject(nil)
+	CreateDataProperty(proto, StringKey("method"), Number(0))
+	objs := make([]*Object, n)
+	for i := range objs {
+		o := NewObject(proto)
+		for _, k := range []string{"id", "name", "x", "y", "z", "w", "parent", "children", "visible", "value"} {
+			CreateDataProperty(o, StringKey(k), Number(float64(i)))
+		}
+		objs[i] = o
+	}
+	return proto, objs
+}
+
+func BenchmarkGetUncached(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("value")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Get(objs[i%len(objs)], key)
+	}
+}
+
+func BenchmarkGetMonomorphic(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("value")
+	var c InlineCache
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(objs[i%len(objs)], key)
+	}
+}
+
+func BenchmarkGetPolymorphic(b *testing.B) {
+	proto, objs := newBenchmarkObjects(64)
+	key := StringKey("value")
+	for i, o := range objs {
+		// Give the objects one of three shapes
+		if i%3 != 0 {
+			o.SetPrototypeOf(nil)
+			o.SetPrototypeOf(proto)
+			CreateDataProperty(o, StringKey("extra"+strconv.Itoa(i%3)), Null())
+		}
+	}
+	var c InlineCache
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(objs[i%len(objs)], key)
+	}
+}
+
+func BenchmarkGetInheritedUncached(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("method")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Get(objs[i%len(objs)], key)
+	}
+}
+
+func BenchmarkGetInheritedCached(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("method")
+	var c InlineCache
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(objs[i%len(objs)], key)
+	}
+}
+
+func BenchmarkSetUncached(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("x")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Set(objs[i%len(objs)], key, Number(float64(i)), true)
+	}
+}
+
+func BenchmarkSetCached(b *testing.B) {
+	_, objs := newBenchmarkObjects(64)
+	key := StringKey("x")
+	var c InlineCache
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(objs[i%len(objs)], key, Number(float64(i)), true)
+	}
+}
+
+func BenchmarkCreateObjects(b *testing.B) {
+	proto := NewObject(nil)
+	keys := []PropertyKey{StringKey("x"), StringKey("y"), StringKey("z")}
+	for i := 0; i < b.N; i++ {
+		o := NewObject(proto)
+		for _, k := range keys {
+			CreateDataProperty(o, k, Number(float64(i)))
+		}
+	}
+}
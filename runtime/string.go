@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// String is an immutable ECMAScript string: a sequence of UTF-16 code
+// units which may contain unpaired surrogates.
+type String struct {
+	key   string   // WTF-8 encoding of the code units, unique per string
+	units []uint16 // code units; nil when the string is pure ASCII
+}
+
+// NewString creates a String from the UTF-8 (or WTF-8) string s.
+func NewString(s string) *String {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			u := decodeWTF8(s)
+			if !utf8.ValidString(s) {
+				s = encodeWTF8(u)
+			}
+			return &String{key: s, units: u}
+		}
+	}
+	return &String{key: s}
+}
+
+// NewStringFromUTF16 creates a String from a sequence of code units.
+func NewStringFromUTF16(units []uint16) *String {
+	for _, u := range units {
+		if u >= utf8.RuneSelf {
+			u := append([]uint16(nil), units...)
+			return &String{key: encodeWTF8(u), units: u}
+		}
+	}
+	b := make([]byte, len(units))
+	for i, u := range units {
+		b[i] = byte(u)
+	}
+	return &String{key: string(b)}
+}
+
+// Len returns the number of code units in s.
+func (s *String) Len() int {
+	if s.units == nil {
+		return len(s.key)
+	}
+	return len(s.units)
+}
+
+// At returns the code unit at index i.
+func (s *String) At(i int) uint16 {
+	if s.units == nil {
+		return uint16(s.key[i])
+	}
+	return s.units[i]
+}
+
+// Units returns the code units of s.
+func (s *String) Units() []uint16 {
+	if s.units != nil {
+		return append([]uint16(nil), s.units...)
+	}
+	u := make([]uint16, len(s.key))
+	for i := 0; i < len(s.key); i++ {
+		u[i] = uint16(s.key[i])
+	}
+	return u
+}
+
+// Substring returns the code units of s from index i up to, but not
+// including, index j.
+func (s *String) Substring(i, j int) *String {
+	if s.units == nil {
+		return &String{key: s.key[i:j]}
+	}
+	return NewStringFromUTF16(s.units[i:j])
+}
+
+// Concat returns the concatenation of s and t.
+func (s *String) Concat(t *String) *String {
+	if s.units == nil && t.units == nil {
+		return &String{key: s.key + t.key}
+	}
+	return NewStringFromUTF16(append(s.Units(), t.Units()...))
+}
+
+// Equals reports whether s and t hold the same code units.
+func (s *String) Equals(t *String) bool {
+	return s.key == t.key
+}
+
+// Compare compares s and t by code unit values and returns -1, 0 or +1.
+func (s *String) Compare(t *String) int {
+	if s.units == nil && t.units == nil {
+		return strings.Compare(s.key, t.key)
+	}
+	n := s.Len()
+	if t.Len() < n {
+		n = t.Len()
+	}
+	for i := 0; i < n; i++ {
+		a, b := s.At(i), t.At(i)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case s.Len() < t.Len():
+		return -1
+	case s.Len() > t.Len():
+		return 1
+	}
+	return 0
+}
+
+// String returns s as UTF-8, replacing unpaired surrogates by U+FFFD.
+func (s *String) String() string {
+	if s.units == nil {
+		return s.key
+	}
+	return string(utf16.Decode(s.units))
+}
+
+// encodeWTF8 encodes code units as UTF-8, extended to encode unpaired
+// surrogates like any other code point so that the result is unique.
+func encodeWTF8(units []uint16) string {
+	var b strings.Builder
+	for i := 0; i < len(units); i++ {
+		u := rune(units[i])
+		if utf16.IsSurrogate(u) && u < 0xDC00 && i+1 < len(units) {
+			if r := utf16.DecodeRune(u, rune(units[i+1])); r != utf8.RuneError {
+				b.WriteRune(r)
+				i++
+				continue
+			}
+		}
+		if utf16.IsSurrogate(u) {
+			b.WriteByte(byte(0xE0 | u>>12))
+			b.WriteByte(byte(0x80 | u>>6&0x3F))
+			b.WriteByte(byte(0x80 | u&0x3F))
+			continue
+		}
+		b.WriteRune(u)
+	}
+	return b.String()
+}
+
+// decodeWTF8 is the inverse of encodeWTF8. Invalid bytes decode to U+FFFD.
+func decodeWTF8(s string) []uint16 {
+	units := make([]uint16, 0, len(s))
+	for i := 0; i < len(s); {
+		// Encoded surrogates: ED A0..BF 80..BF
+		if i+2 < len(s) && s[i] == 0xED && s[i+1]&0xE0 == 0xA0 && s[i+2]&0xC0 == 0x80 {
+			units = append(units, uint16(0xD000|uint16(s[i+1]&0x3F)<<6|uint16(s[i+2]&0x3F)))
+			i += 3
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r >= 0x10000 {
+			hi, lo := utf16.EncodeRune(r)
+			units = append(units, uint16(hi), uint16(lo))
+		} else {
+			units = append(units, uint16(r))
+		}
+		i += size
+	}
+	return units
+}
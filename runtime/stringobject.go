@@ -0,0 +1,57 @@
+package runtime
+
+// stringObject implements the internal methods of String exotic
+// objects: the code units of the wrapped string and its length are
+// read-only own properties, resolved from [[StringData]] on access
+// rather than stored.
+type stringObject struct {
+	ordinary
+}
+
+// stringGetOwnProperty returns the property of String objects wrapping
+// s for key if it is an index into s or length.
+func stringGetOwnProperty(s *String, key PropertyKey) (PropertyDescriptor, bool) {
+	if key == StringKey("length") {
+		return DataDescriptor(Number(float64(s.Len())), false, false, false), true
+	}
+	i, ok := key.arrayIndex()
+	if !ok || int64(i) >= int64(s.Len()) {
+		return PropertyDescriptor{}, false
+	}
+	return DataDescriptor(s.Substring(int(i), int(i)+1).Value(), false, true, false), true
+}
+
+func (m stringObject) getOwnProperty(o *Object, key PropertyKey) (PropertyDescriptor, bool) {
+	if desc, ok := stringGetOwnProperty(o.primitive().AsString(), key); ok {
+		return desc, true
+	}
+	return m.ordinary.getOwnProperty(o, key)
+}
+
+func (m stringObject) defineOwnProperty(o *Object, key PropertyKey, desc PropertyDescriptor) bool {
+	if current, ok := stringGetOwnProperty(o.primitive().AsString(), key); ok {
+		return validateAndApplyPropertyDescriptor(nil, key, o.extensible, desc, current, true)
+	}
+	return m.ordinary.defineOwnProperty(o, key, desc)
+}
+
+func (m stringObject) ownPropertyKeys(o *Object) []PropertyKey {
+	n := o.primitive().AsString().Len()
+	ordinaryKeys := m.ordinary.ownPropertyKeys(o)
+	keys := make([]PropertyKey, 0, n+1+len(ordinaryKeys))
+	for i := 0; i < n; i++ {
+		keys = append(keys, StringKey(numberToString(float64(i))))
+	}
+
+	// Indices past the end of the string come first, then length,
+	// which is created before any other property
+	i := 0
+	for ; i < len(ordinaryKeys); i++ {
+		if _, ok := ordinaryKeys[i].arrayIndex(); !ok {
+			break
+		}
+	}
+	keys = append(keys, ordinaryKeys[:i]...)
+	keys = append(keys, StringKey("length"))
+	return append(keys, ordinaryKeys[i:]...)
+}
@@ -0,0 +1,92 @@
+package runtime
+
+// Symbol is a unique, immutable ECMAScript symbol value.
+type Symbol struct {
+	description *String // nil when the description is undefined
+}
+
+// NewSymbol creates a new unique symbol. description may be nil.
+func NewSymbol(description *String) *Symbol {
+	return &Symbol{description}
+}
+
+// Description returns the [[Description]] of s, or undefined.
+func (s *Symbol) Description() Value {
+	if s.description == nil {
+		return Undefined()
+	}
+	return s.description.Value()
+}
+
+// String implements SymbolDescriptiveString.
+func (s *Symbol) String() string {
+	if s.description == nil {
+		return "Symbol()"
+	}
+	return "Symbol(" + s.description.String() + ")"
+}
+
+// Well-known symbols shared by all realms
+var (
+	SymbolAsyncIterator      = NewSymbol(NewString("Symbol.asyncIterator"))
+	SymbolHasInstance        = NewSymbol(NewString("Symbol.hasInstance"))
+	SymbolIsConcatSpreadable = NewSymbol(NewString("Symbol.isConcatSpreadable"))
+	SymbolIterator           = NewSymbol(NewString("Symbol.iterator"))
+	SymbolMatch              = NewSymbol(NewString("Symbol.match"))
+	SymbolMatchAll           = NewSymbol(NewString("Symbol.matchAll"))
+	SymbolReplace            = NewSymbol(NewString("Symbol.replace"))
+	SymbolSearch             = NewSymbol(NewString("Symbol.search"))
+	SymbolSpecies            = NewSymbol(NewString("Symbol.species"))
+	SymbolSplit              = NewSymbol(NewString("Symbol.split"))
+	SymbolToPrimitive        = NewSymbol(NewString("Symbol.toPrimitive"))
+	SymbolToStringTag        = NewSymbol(NewString("Symbol.toStringTag"))
+	SymbolUnscopables        = NewSymbol(NewString("Symbol.unscopables"))
+)
+
+// PropertyKey is a string or symbol naming an object property.
+// PropertyKeys are comparable and can be used as map keys.
+type PropertyKey struct {
+	str string  // WTF-8 encoding of a string key
+	sym *Symbol // non-nil for symbol keys
+}
+
+// StringKey returns the property key for the UTF-8 string s.
+func StringKey(s string) PropertyKey {
+	return PropertyKey{str: NewString(s).key}
+}
+
+// KeyOf returns the property key for the string s.
+func KeyOf(s *String) PropertyKey {
+	return PropertyKey{str: s.key}
+}
+
+// SymbolKey returns the property key for the symbol s.
+func SymbolKey(s *Symbol) PropertyKey {
+	return PropertyKey{sym: s}
+}
+
+// IsSymbol reports whether k is a symbol key.
+func (k PropertyKey) IsSymbol() bool {
+	return k.sym != nil
+}
+
+// Symbol returns the symbol of a symbol key.
+func (k PropertyKey) Symbol() *Symbol {
+	return k.sym
+}
+
+// Value returns k as a String or Symbol value.
+func (k PropertyKey) Value() Value {
+	if k.sym != nil {
+		return k.sym.Value()
+	}
+	return NewString(k.str).Value()
+}
+
+// String returns k as UTF-8 for debugging.
+func (k PropertyKey) String() string {
+	if k.sym != nil {
+		return "[" + k.sym.String() + "]"
+	}
+	return k.Value().String()
+}
@@ -0,0 +1,226 @@
+// Package runtime implements the ECMAScript language types and the
+// abstract operations defined over them.
+package runtime
+
+import (
+	"math"
+	"math/big"
+)
+
+// Kind is the ECMAScript language type of a Value.
+type Kind uint8
+
+// Definition of the language types
+const (
+	KindUndefined Kind = iota
+	KindNull
+	KindBoolean
+	KindNumber
+	KindBigInt
+	KindString
+	KindSymbol
+	KindObject
+)
+
+var kindNames = [...]string{
+	KindUndefined: "Undefined",
+	KindNull:      "Null",
+	KindBoolean:   "Boolean",
+	KindNumber:    "Number",
+	KindBigInt:    "BigInt",
+	KindString:    "String",
+	KindSymbol:    "Symbol",
+	KindObject:    "Object",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Value is an ECMAScript language value. It is a small tagged struct
+// passed by value: booleans and numbers are stored inline and never
+// allocate, other types hold a pointer. The zero Value is undefined.
+type Value struct {
+	kind Kind
+	num  float64     // Boolean (0 or 1) and Number payload
+	ref  interface{} // *big.Int, *String, *Symbol or *Object
+}
+
+// Undefined returns the undefined value.
+func Undefined() Value {
+	return Value{}
+}
+
+// Null returns the null value.
+func Null() Value {
+	return Value{kind: KindNull}
+}
+
+// Bool returns the Boolean value b.
+func Bool(b bool) Value {
+	if b {
+		return Value{kind: KindBoolean, num: 1}
+	}
+	return Value{kind: KindBoolean}
+}
+
+// Number returns the Number value f.
+func Number(f float64) Value {
+	return Value{kind: KindNumber, num: f}
+}
+
+// BigInt returns the BigInt value n. n must not be modified afterwards.
+func BigInt(n *big.Int) Value {
+	return Value{kind: KindBigInt, ref: n}
+}
+
+// Str returns the String value holding the UTF-8 string s.
+func Str(s string) Value {
+	return NewString(s).Value()
+}
+
+// Value returns s as a language value.
+func (s *String) Value() Value {
+	return Value{kind: KindString, ref: s}
+}
+
+// Value returns s as a language value.
+func (s *Symbol) Value() Value {
+	return Value{kind: KindSymbol, ref: s}
+}
+
+// Value returns o as a language value.
+func (o *Object) Value() Value {
+	return Value{kind: KindObject, ref: o}
+}
+
+// Kind returns the language type of v.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// IsUndefined reports whether v is undefined.
+func (v Value) IsUndefined() bool {
+	return v.kind == KindUndefined
+}
+
+// IsNull reports whether v is null.
+func (v Value) IsNull() bool {
+	return v.kind == KindNull
+}
+
+// IsNullish reports whether v is undefined or null.
+func (v Value) IsNullish() bool {
+	return v.kind <= KindNull
+}
+
+// IsObject reports whether v is an object.
+func (v Value) IsObject() bool {
+	return v.kind == KindObject
+}
+
+// AsBool returns the payload of a Boolean value.
+func (v Value) AsBool() bool {
+	return v.num != 0
+}
+
+// AsNumber returns the payload of a Number value.
+func (v Value) AsNumber() float64 {
+	return v.num
+}
+
+// AsBigInt returns the payload of a BigInt value. It must not be modified.
+func (v Value) AsBigInt() *big.Int {
+	n, _ := v.ref.(*big.Int)
+	return n
+}
+
+// AsString returns the payload of a String value.
+func (v Value) AsString() *String {
+	s, _ := v.ref.(*String)
+	return s
+}
+
+// AsSymbol returns the payload of a Symbol value.
+func (v Value) AsSymbol() *Symbol {
+	s, _ := v.ref.(*Symbol)
+	return s
+}
+
+// AsObject returns the payload of an Object value.
+func (v Value) AsObject() *Object {
+	o, _ := v.ref.(*Object)
+	return o
+}
+
+// String returns a representation of v for debugging. Unlike ToString
+// it never invokes user code.
+func (v Value) String() string {
+	switch v.kind {
+	case KindUndefined:
+		return "undefined"
+	case KindNull:
+		return "null"
+	case KindBoolean:
+		if v.AsBool() {
+			return "true"
+		}
+		return "false"
+	case KindNumber:
+		return numberToString(v.num)
+	case KindBigInt:
+		return v.AsBigInt().String() + "n"
+	case KindString:
+		return v.AsString().String()
+	case KindSymbol:
+		return v.AsSymbol().String()
+	default:
+		return "[object Object]"
+	}
+}
+
+// SameValue reports whether x and y are the same value. It differs
+// from === in that NaN is the same as NaN and +0 differs from -0.
+func SameValue(x, y Value) bool {
+	if x.kind == KindNumber && y.kind == KindNumber {
+		if math.IsNaN(x.num) && math.IsNaN(y.num) {
+			return true
+		}
+		return x.num == y.num && math.Signbit(x.num) == math.Signbit(y.num)
+	}
+	return sameValueNonNumber(x, y)
+}
+
+// SameValueZero is SameValue except that +0 and -0 are the same.
+func SameValueZero(x, y Value) bool {
+	if x.kind == KindNumber && y.kind == KindNumber {
+		return x.num == y.num || math.IsNaN(x.num) && math.IsNaN(y.num)
+	}
+	return sameValueNonNumber(x, y)
+}
+
+// IsStrictlyEqual implements the === operator.
+func IsStrictlyEqual(x, y Value) bool {
+	if x.kind == KindNumber && y.kind == KindNumber {
+		return x.num == y.num
+	}
+	return sameValueNonNumber(x, y)
+}
+
+func sameValueNonNumber(x, y Value) bool {
+	if x.kind != y.kind {
+		return false
+	}
+	switch x.kind {
+	case KindUndefined, KindNull:
+		return true
+	case KindBoolean:
+		return x.num == y.num
+	case KindBigInt:
+		return x.AsBigInt().Cmp(y.AsBigInt()) == 0
+	case KindString:
+		return x.AsString().Equals(y.AsString())
+	default:
+		return x.ref == y.ref
+	}
+}
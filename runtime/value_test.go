@@ -0,0 +1,270 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func TestSameValue(t *testing.T) {
+	nan := Number(math.NaN())
+	zero, negZero := Number(0), Number(math.Copysign(0, -1))
+	obj := NewObject(nil).Value()
+	sym := NewSymbol(nil).Value()
+
+	tests := []struct {
+		x, y                           Value
+		sameValue, sameValueZero, eqeq bool
+	}{
+		{nan, nan, true, true, false},
+		{zero, negZero, false, true, true},
+		{Number(1), Number(1), true, true, true},
+		{Undefined(), Null(), false, false, false},
+		{Undefined(), Undefined(), true, true, true},
+		{Bool(true), Bool(true), true, true, true},
+		{Bool(true), Number(1), false, false, false},
+		{Str("abc"), Str("abc"), true, true, true},
+		{Str("abc"), Str("abd"), false, false, false},
+		{BigInt(big.NewInt(7)), BigInt(big.NewInt(7)), true, true, true},
+		{obj, obj, true, true, true},
+		{obj, NewObject(nil).Value(), false, false, false},
+		{sym, sym, true, true, true},
+		{sym, NewSymbol(nil).Value(), false, false, false},
+	}
+
+	for _, c := range tests {
+		test.AssertEqual(t, SameValue(c.x, c.y), c.sameValue)
+		test.AssertEqual(t, SameValueZero(c.x, c.y), c.sameValueZero)
+		test.AssertEqual(t, IsStrictlyEqual(c.x, c.y), c.eqeq)
+	}
+}
+
+func TestString(t *testing.T) {
+	s := NewString("a\U0001F600b")
+	test.AssertEqual(t, s.Len(), 4)
+	test.AssertEqual(t, s.At(1), uint16(0xD83D))
+	test.AssertEqual(t, s.At(2), uint16(0xDE00))
+	test.AssertEqual(t, s.String(), "a\U0001F600b")
+
+	// Unpaired surrogates survive a round trip and stay distinct keys
+	lone := NewStringFromUTF16([]uint16{'x', 0xD83D})
+	other := NewStringFromUTF16([]uint16{'x', 0xDE00})
+	test.AssertEqual(t, lone.Len(), 2)
+	test.AssertEqual(t, lone.Equals(other), false)
+	test.AssertEqual(t, NewString(lone.key).Equals(lone), true)
+	test.AssertEqual(t, s.Substring(0, 2).Concat(s.Substring(2, 4)).Equals(s), true)
+	test.AssertEqual(t, s.Substring(1, 2).Equals(NewStringFromUTF16([]uint16{0xD83D})), true)
+
+	// Ordering is by code unit, not by code point
+	test.AssertEqual(t, NewString("\U0001F600").Compare(NewString("\uFFFF")), -1)
+	test.AssertEqual(t, NewString("ab").Compare(NewString("abc")), -1)
+	test.AssertEqual(t, NewString("b").Compare(NewString("abc")), 1)
+	test.AssertEqual(t, NewString("é").Compare(NewString("é")), 0)
+}
+
+func TestNumberToString(t *testing.T) {
+	tests := []struct {
+		in  float64
+		out string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "0"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "Infinity"},
+		{math.Inf(-1), "-Infinity"},
+		{1, "1"},
+		{-1.5, "-1.5"},
+		{123.456, "123.456"},
+		{0.1, "0.1"},
+		{0.000001, "0.000001"},
+		{0.0000001, "1e-7"},
+		{1.5e-7, "1.5e-7"},
+		{1e21, "1e+21"},
+		{1e20, "100000000000000000000"},
+		{123456789012345680000, "123456789012345680000"},
+		{1.2345e25, "1.2345e+25"},
+		{5e-324, "5e-324"},
+		{1.7976931348623157e+308, "1.7976931348623157e+308"},
+	}
+
+	for _, c := range tests {
+		test.AssertEqual(t, numberToString(c.in), c.out)
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	tests := []struct {
+		in  string
+		out float64
+	}{
+		{"", 0},
+		{"  \n\t ", 0},
+		{"42", 42},
+		{"  42 ", 42},
+		{"-42.5", -42.5},
+		{"+.5", 0.5},
+		{"5.", 5},
+		{"1e3", 1000},
+		{"1E-3", 0.001},
+		{"0x1F", 31},
+		{"0o17", 15},
+		{"0B101", 5},
+		{"Infinity", math.Inf(1)},
+		{"-Infinity", math.Inf(-1)},
+		{"1e400", math.Inf(1)},
+		{"0xFFFFFFFFFFFFFFFFF", 295147905179352830000},
+		{"007", 7},
+	}
+
+	for _, c := range tests {
+		n, err := ToNumber(Str(c.in))
+		test.AssertEqual(t, err, nil)
+		test.AssertEqual(t, n, c.out)
+	}
+
+	for _, in := range []string{"abc", "1_000", "-0x10", "0x", "1e", ".", "+-1", "inf", "NaN", "1 2", "0x1p3", "infinity"} {
+		n, _ := ToNumber(Str(in))
+		if !math.IsNaN(n) {
+			t.Fatalf("ToNumber(%q) = %v, want NaN", in, n)
+		}
+	}
+
+	n, err := ToNumber(Undefined())
+	test.AssertEqual(t, math.IsNaN(n), true)
+	test.AssertEqual(t, err, nil)
+	n, _ = ToNumber(Null())
+	test.AssertEqual(t, n, 0.0)
+	n, _ = ToNumber(Bool(true))
+	test.AssertEqual(t, n, 1.0)
+
+	_, err = ToNumber(NewSymbol(nil).Value())
+	test.AssertEqual(t, err.(*Exception).Type, TypeError)
+	_, err = ToNumber(BigInt(big.NewInt(1)))
+	test.AssertEqual(t, err.(*Exception).Type, TypeError)
+}
+
+func TestToBoolean(t *testing.T) {
+	falsy := []Value{Undefined(), Null(), Bool(false), Number(0), Number(math.NaN()), Str(""), BigInt(new(big.Int))}
+	truthy := []Value{Bool(true), Number(-1), Str("0"), BigInt(big.NewInt(-1)), NewSymbol(nil).Value(), NewObject(nil).Value()}
+
+	for _, v := range falsy {
+		test.AssertEqual(t, ToBoolean(v), false)
+	}
+	for _, v := range truthy {
+		test.AssertEqual(t, ToBoolean(v), true)
+	}
+}
+
+// newConvertible creates an object whose valueOf and toString methods
+// return the given values and record the order they were called in.
+func newConvertible(valueOf, toString Value, calls *[]string) *Object {
+	o := NewObject(nil)
+	CreateDataProperty(o, StringKey("valueOf"), NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		*calls = append(*calls, "valueOf")
+		return valueOf, nil
+	}).Value())
+	CreateDataProperty(o, StringKey("toString"), NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		*calls = append(*calls, "toString")
+		return toString, nil
+	}).Value())
+	return o
+}
+
+func TestToPrimitive(t *testing.T) {
+	var calls []string
+	o := newConvertible(Number(1), Str("one"), &calls).Value()
+
+	v, err := ToPrimitive(o, HintDefault)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.AsNumber(), 1.0)
+
+	s, err := ToString(o)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, s.String(), "one")
+	test.AssertEqual(t, len(calls), 2)
+	test.AssertEqual(t, calls[1], "toString")
+
+	// Object results are skipped
+	calls = nil
+	o = newConvertible(NewObject(nil).Value(), Str("7"), &calls).Value()
+	n, err := ToNumber(o)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, n, 7.0)
+	test.AssertEqual(t, len(calls), 2)
+
+	o = newConvertible(NewObject(nil).Value(), NewObject(nil).Value(), &calls).Value()
+	_, err = ToPrimitive(o, HintString)
+	test.AssertEqual(t, err.(*Exception).Type, TypeError)
+
+	// @@toPrimitive takes precedence and receives the hint
+	var hint string
+	exotic := NewObject(nil)
+	CreateDataProperty(exotic, SymbolKey(SymbolToPrimitive), NewNativeFunction(func(this Value, args []Value) (Value, error) {
+		hint = args[0].String()
+		return Str("exotic"), nil
+	}).Value())
+	v, err = ToPrimitive(exotic.Value(), HintNumber)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, v.String(), "exotic")
+	test.AssertEqual(t, hint, "number")
+
+	CreateDataProperty(exotic, SymbolKey(SymbolToPrimitive), Number(1))
+	_, err = ToPrimitive(exotic.Value(), HintNumber)
+	test.AssertEqual(t, err.(*Exception).Type, TypeError)
+}
+
+func TestToPropertyKey(t *testing.T) {
+	k, err := ToPropertyKey(Number(1.5))
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, k, StringKey("1.5"))
+
+	sym := NewSymbol(NewString("s"))
+	k, err = ToPropertyKey(sym.Value())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, k, SymbolKey(sym))
+	test.AssertEqual(t, k.IsSymbol(), true)
+
+	var calls []string
+	k, err = ToPropertyKey(newConvertible(Number(1), Str("key"), &calls).Value())
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, k, StringKey("key"))
+
+	_, err = ToString(sym.Value())
+	test.AssertEqual(t, err.(*Exception).Type, TypeError)
+}
+
+func TestIsLooselyEqual(t *testing.T) {
+	var calls []string
+	obj := newConvertible(Number(2), Str("two"), &calls).Value()
+
+	tests := []struct {
+		x, y Value
+		out  bool
+	}{
+		{Undefined(), Null(), true},
+		{Null(), Number(0), false},
+		{Undefined(), Bool(false), false},
+		{Number(1), Str(" 1 "), true},
+		{Str("0x10"), Number(16), true},
+		{Number(math.NaN()), Str("NaN"), false},
+		{Bool(true), Str("1"), true},
+		{Bool(false), Str(""), true},
+		{BigInt(big.NewInt(10)), Str("10"), true},
+		{Str("1.5"), BigInt(big.NewInt(1)), false},
+		{BigInt(big.NewInt(2)), Number(2), true},
+		{Number(2.5), BigInt(big.NewInt(2)), false},
+		{BigInt(big.NewInt(1)), Number(math.Inf(1)), false},
+		{obj, Number(2), true},
+		{Str("2"), obj, true},
+		{obj, Null(), false},
+		{obj, obj, true},
+	}
+
+	for _, c := range tests {
+		eq, err := IsLooselyEqual(c.x, c.y)
+		test.AssertEqual(t, err, nil)
+		test.AssertEqual(t, eq, c.out)
+	}
+}
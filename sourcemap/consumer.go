@@ -0,0 +1,152 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Consumer answers queries against a parsed source map.
+type Consumer struct {
+	file     string
+	sources  []string
+	contents []*string
+	lines    [][]Mapping // mappings grouped by generated line, sorted by column
+}
+
+// Parse parses a version 3 source map. Index maps (with "sections")
+// are not supported.
+func Parse(data []byte) (*Consumer, error) {
+	var sm struct {
+		sourceMap
+		Sections json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, err
+	}
+	if sm.Version != 3 {
+		return nil, fmt.Errorf("sourcemap: unsupported version %d", sm.Version)
+	}
+	if sm.Sections != nil {
+		return nil, errors.New("sourcemap: index maps are not supported")
+	}
+
+	c := &Consumer{
+		file:     sm.File,
+		sources:  make([]string, len(sm.Sources)),
+		contents: sm.SourcesContent,
+	}
+	for i, src := range sm.Sources {
+		if sm.SourceRoot != "" && !strings.HasSuffix(sm.SourceRoot, "/") {
+			src = sm.SourceRoot + "/" + src
+		} else {
+			src = sm.SourceRoot + src
+		}
+		c.sources[i] = src
+	}
+
+	if err := c.decodeMappings(sm.Mappings, sm.Names); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// decodeMappings decodes the mappings field into c.lines.
+func (c *Consumer) decodeMappings(mappings string, names []string) error {
+	var fields [5]int // generated column, source, original line, original column, name
+	for _, line := range strings.Split(mappings, ";") {
+		var segs []Mapping
+		fields[0] = 0
+
+		for _, seg := range strings.Split(line, ",") {
+			if seg == "" {
+				continue
+			}
+			n := 0
+			for n < len(fields) && seg != "" {
+				v, size, err := decodeVLQ(seg)
+				if err != nil {
+					return err
+				}
+				fields[n] += v
+				seg = seg[size:]
+				n++
+			}
+			if seg != "" || n == 2 || n == 3 {
+				return fmt.Errorf("sourcemap: invalid segment with %d fields", n)
+			}
+
+			m := Mapping{Generated: Position{len(c.lines), fields[0]}}
+			if n >= 4 {
+				if fields[1] < 0 || fields[1] >= len(c.sources) {
+					return fmt.Errorf("sourcemap: source index %d out of range", fields[1])
+				}
+				m.Source = c.sources[fields[1]]
+				m.Original = Position{fields[2], fields[3]}
+			}
+			if n == 5 {
+				if fields[4] < 0 || fields[4] >= len(names) {
+					return fmt.Errorf("sourcemap: name index %d out of range", fields[4])
+				}
+				m.Name = names[fields[4]]
+			}
+			segs = append(segs, m)
+		}
+
+		sort.SliceStable(segs, func(i, j int) bool {
+			return segs[i].Generated.Column < segs[j].Generated.Column
+		})
+		c.lines = append(c.lines, segs)
+	}
+	return nil
+}
+
+// File returns the name of the generated file the map describes.
+func (c *Consumer) File() string {
+	return c.file
+}
+
+// Sources returns the original sources, prefixed with the source root.
+func (c *Consumer) Sources() []string {
+	return append([]string{}, c.sources...)
+}
+
+// SourceContent returns the embedded content of source, if any.
+func (c *Consumer) SourceContent(source string) (string, bool) {
+	for i, src := range c.sources {
+		if src == source && i < len(c.contents) && c.contents[i] != nil {
+			return *c.contents[i], true
+		}
+	}
+	return "", false
+}
+
+// OriginalPosition returns the mapping covering the generated position
+// pos: the closest segment on the same line starting at or before pos.
+// It reports false if no segment covers pos or the segment maps to no
+// original location.
+func (c *Consumer) OriginalPosition(pos Position) (Mapping, bool) {
+	if pos.Line < 0 || pos.Line >= len(c.lines) {
+		return Mapping{}, false
+	}
+	segs := c.lines[pos.Line]
+	i := sort.Search(len(segs), func(i int) bool {
+		return segs[i].Generated.Column > pos.Column
+	})
+	if i == 0 {
+		return Mapping{}, false
+	}
+	m := segs[i-1]
+	return m, m.Source != ""
+}
+
+// Mappings returns every mapping in generated order.
+func (c *Consumer) Mappings() []Mapping {
+	var ms []Mapping
+	for _, segs := range c.lines {
+		ms = append(ms, segs...)
+	}
+	return ms
+}
@@ -0,0 +1,161 @@
+// Package sourcemap generates and consumes source maps as specified by
+// the Source Map Revision 3 proposal (https://sourcemaps.info/spec.html).
+package sourcemap
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Position is a zero-based line and column in a source file.
+// Columns are counted in UTF-16 code units, as required by the spec.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Mapping links a position in the generated file to a position in
+// an original source. A Mapping with an empty Source maps a generated
+// position to no original location.
+type Mapping struct {
+	Generated Position
+	Source    string
+	Original  Position
+	Name      string // original identifier name, if any
+}
+
+// sourceMap is the JSON representation of a version 3 source map.
+type sourceMap struct {
+	Version        int       `json:"version"`
+	File           string    `json:"file,omitempty"`
+	SourceRoot     string    `json:"sourceRoot,omitempty"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent,omitempty"`
+	Names          []string  `json:"names"`
+	Mappings       string    `json:"mappings"`
+}
+
+// Generator accumulates mappings and serialises them as a source map.
+type Generator struct {
+	file     string
+	root     string
+	mappings []Mapping
+	sources  []string
+	names    []string
+	srcIndex map[string]int
+	nameIdx  map[string]int
+	contents map[string]string
+}
+
+// NewGenerator creates a Generator for the generated file named file.
+func NewGenerator(file, sourceRoot string) *Generator {
+	return &Generator{
+		file:     file,
+		root:     sourceRoot,
+		srcIndex: make(map[string]int),
+		nameIdx:  make(map[string]int),
+		contents: make(map[string]string),
+	}
+}
+
+// AddMapping records a mapping. Mappings may be added in any order.
+func (g *Generator) AddMapping(m Mapping) {
+	if m.Source != "" {
+		g.source(m.Source)
+		if m.Name != "" {
+			if _, ok := g.nameIdx[m.Name]; !ok {
+				g.nameIdx[m.Name] = len(g.names)
+				g.names = append(g.names, m.Name)
+			}
+		}
+	}
+	g.mappings = append(g.mappings, m)
+}
+
+// SetSourceContent embeds the content of an original source in the
+// map's sourcesContent field.
+func (g *Generator) SetSourceContent(source, content string) {
+	g.source(source)
+	g.contents[source] = content
+}
+
+// source returns the index of source in the sources list, adding it
+// if it is not there yet.
+func (g *Generator) source(source string) int {
+	i, ok := g.srcIndex[source]
+	if !ok {
+		i = len(g.sources)
+		g.srcIndex[source] = i
+		g.sources = append(g.sources, source)
+	}
+	return i
+}
+
+// MarshalJSON encodes the source map.
+func (g *Generator) MarshalJSON() ([]byte, error) {
+	sm := sourceMap{
+		Version:    3,
+		File:       g.file,
+		SourceRoot: g.root,
+		Sources:    append([]string{}, g.sources...),
+		Names:      append([]string{}, g.names...),
+		Mappings:   g.encodeMappings(),
+	}
+	if len(g.contents) > 0 {
+		sm.SourcesContent = make([]*string, len(g.sources))
+		for i, src := range g.sources {
+			if c, ok := g.contents[src]; ok {
+				sm.SourcesContent[i] = &c
+			}
+		}
+	}
+	return json.Marshal(sm)
+}
+
+// encodeMappings encodes the mappings field. Every field except the
+// generated line is stored relative to the previous segment; the
+// generated column restarts at zero on every line.
+func (g *Generator) encodeMappings() string {
+	ms := append([]Mapping{}, g.mappings...)
+	sort.SliceStable(ms, func(i, j int) bool {
+		a, b := ms[i].Generated, ms[j].Generated
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	var b strings.Builder
+	line, prevCol, prevSrc, prevLine, prevOrigCol, prevName := 0, 0, 0, 0, 0, 0
+	for i, m := range ms {
+		if m.Generated.Line != line {
+			for line < m.Generated.Line {
+				b.WriteByte(';')
+				line++
+			}
+			prevCol = 0
+		} else if i > 0 {
+			b.WriteByte(',')
+		}
+
+		encodeVLQ(&b, m.Generated.Column-prevCol)
+		prevCol = m.Generated.Column
+		if m.Source == "" {
+			continue
+		}
+
+		src := g.srcIndex[m.Source]
+		encodeVLQ(&b, src-prevSrc)
+		encodeVLQ(&b, m.Original.Line-prevLine)
+		encodeVLQ(&b, m.Original.Column-prevOrigCol)
+		prevSrc, prevLine, prevOrigCol = src, m.Original.Line, m.Original.Column
+
+		if m.Name != "" {
+			name := g.nameIdx[m.Name]
+			encodeVLQ(&b, name-prevName)
+			prevName = name
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package sourcemap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valaymerick/doletto/test"
+)
+
+func TestVLQ(t *testing.T) {
+	tests := []struct {
+		in  int
+		out string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{16, "gB"},
+		{123, "2H"},
+		{-123456, "hkxH"},
+	}
+
+	for _, c := range tests {
+		var b strings.Builder
+		encodeVLQ(&b, c.in)
+		test.AssertEqual(t, b.String(), c.out)
+
+		n, size, err := decodeVLQ(c.out)
+		test.AssertEqual(t, err, nil)
+		test.AssertEqual(t, n, c.in)
+		test.AssertEqual(t, size, len(c.out))
+	}
+
+	_, _, err := decodeVLQ("g")
+	test.AssertEqual(t, err, errInvalidVLQ)
+	_, _, err = decodeVLQ("!")
+	test.AssertEqual(t, err, errInvalidVLQ)
+}
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator("out.js", "")
+	g.AddMapping(Mapping{Generated: Position{0, 0}, Source: "a.js", Original: Position{0, 0}})
+	g.AddMapping(Mapping{Generated: Position{0, 9}, Source: "a.js", Original: Position{0, 9}, Name: "foo"})
+	g.AddMapping(Mapping{Generated: Position{2, 4}, Source: "b.js", Original: Position{3, 2}})
+	g.AddMapping(Mapping{Generated: Position{2, 1}})
+	g.SetSourceContent("a.js", "function foo() {}")
+
+	out, err := g.MarshalJSON()
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, string(out), `{"version":3,"file":"out.js","sources":["a.js","b.js"],`+
+		`"sourcesContent":["function foo() {}",null],"names":["foo"],"mappings":"AAAA,SAASA;;C,GCGP"}`)
+}
+
+func TestConsumer(t *testing.T) {
+	g := NewGenerator("out.js", "src")
+	mappings := []Mapping{
+		{Generated: Position{0, 0}, Source: "a.js", Original: Position{0, 0}},
+		{Generated: Position{0, 9}, Source: "a.js", Original: Position{0, 9}, Name: "foo"},
+		{Generated: Position{1, 0}},
+		{Generated: Position{1, 2}, Source: "b.js", Original: Position{10, 4}, Name: "bar"},
+		{Generated: Position{4, 7}, Source: "a.js", Original: Position{2, 0}, Name: "foo"},
+	}
+	for _, m := range mappings {
+		g.AddMapping(m)
+	}
+	g.SetSourceContent("b.js", "let bar")
+
+	out, err := g.MarshalJSON()
+	test.AssertEqual(t, err, nil)
+
+	c, err := Parse(out)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, c.File(), "out.js")
+	test.AssertEqual(t, len(c.Mappings()), len(mappings))
+
+	content, ok := c.SourceContent("src/b.js")
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, content, "let bar")
+	_, ok = c.SourceContent("src/a.js")
+	test.AssertEqual(t, ok, false)
+
+	tests := []struct {
+		gen  Position
+		ok   bool
+		src  string
+		orig Position
+		name string
+	}{
+		{Position{0, 0}, true, "src/a.js", Position{0, 0}, ""},
+		{Position{0, 5}, true, "src/a.js", Position{0, 0}, ""},
+		{Position{0, 12}, true, "src/a.js", Position{0, 9}, "foo"},
+		{Position{1, 1}, false, "", Position{}, ""},
+		{Position{1, 3}, true, "src/b.js", Position{10, 4}, "bar"},
+		{Position{3, 0}, false, "", Position{}, ""},
+		{Position{4, 6}, false, "", Position{}, ""},
+		{Position{4, 7}, true, "src/a.js", Position{2, 0}, "foo"},
+		{Position{9, 0}, false, "", Position{}, ""},
+	}
+
+	for _, tc := range tests {
+		m, ok := c.OriginalPosition(tc.gen)
+		test.AssertEqual(t, ok, tc.ok)
+		if !ok {
+			continue
+		}
+		test.AssertEqual(t, m.Source, tc.src)
+		test.AssertEqual(t, m.Original, tc.orig)
+		test.AssertEqual(t, m.Name, tc.name)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	inputs := []string{
+		`{"version":2,"sources":[],"names":[],"mappings":""}`,
+		`{"version":3,"sections":[]}`,
+		`{"version":3,"sources":[],"names":[],"mappings":"AC"}`,
+		`{"version":3,"sources":["a.js"],"names":[],"mappings":"ACAA"}`,
+		`{"version":3,"sources":["a.js"],"names":[],"mappings":"AAAAC"}`,
+		`{"version":3,"sources":["a.js"],"names":[],"mappings":"A!"}`,
+	}
+
+	for _, in := range inputs {
+		_, err := Parse([]byte(in))
+		if err == nil {
+			t.Fatalf("expected an error for %s", in)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sourcemap
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	vlqBaseShift       = 5
+	vlqBase            = 1 << vlqBaseShift
+	vlqBaseMask        = vlqBase - 1
+	vlqContinuationBit = vlqBase
+)
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// base64Values maps a base64 character to its 6-bit value, or -1.
+var base64Values = func() [256]int {
+	var v [256]int
+	for i := range v {
+		v[i] = -1
+	}
+	for i := 0; i < len(base64Chars); i++ {
+		v[base64Chars[i]] = i
+	}
+	return v
+}()
+
+var errInvalidVLQ = errors.New("sourcemap: invalid base64 VLQ")
+
+// encodeVLQ appends the base64 VLQ encoding of n to b.
+// The sign is stored in the least significant bit of the first digit.
+func encodeVLQ(b *strings.Builder, n int) {
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+	for {
+		digit := v & vlqBaseMask
+		v >>= vlqBaseShift
+		if v > 0 {
+			digit |= vlqContinuationBit
+		}
+		b.WriteByte(base64Chars[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// decodeVLQ decodes one base64 VLQ value from the start of s and
+// returns it together with the number of bytes consumed.
+func decodeVLQ(s string) (int, int, error) {
+	v, shift := 0, uint(0)
+	for i := 0; i < len(s); i++ {
+		digit := base64Values[s[i]]
+		if digit < 0 || shift > 60 {
+			return 0, 0, errInvalidVLQ
+		}
+		v += (digit & vlqBaseMask) << shift
+		if digit&vlqContinuationBit == 0 {
+			n := v >> 1
+			if v&1 == 1 {
+				n = -n
+			}
+			return n, i + 1, nil
+		}
+		shift += vlqBaseShift
+	}
+	return 0, 0, errInvalidVLQ
+}